@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/mine/fileWatch/internal/api"
+	"github.com/mine/fileWatch/internal/database"
+	"github.com/mine/fileWatch/internal/monitor"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "Web服务监听地址")
+	replayFile := flag.String("replay", "", "启动时回放的fs_usage捕获文件")
+	replayRealtime := flag.Bool("replay-realtime", false, "按捕获中的原始时间间隔回放")
+	replayDate := flag.String("replay-date", "", "捕获开始的日期（YYYY-MM-DD），捕获文件没有记录开始时间时需要指定")
+	flag.Parse()
+
+	var replayBase time.Time
+	if *replayDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", *replayDate, time.Local)
+		if err != nil {
+			log.Fatalf("无效的回放日期: %v", err)
+		}
+		replayBase = t
+	}
+
+	if err := database.InitDB(""); err != nil {
+		log.Fatalf("初始化数据存储失败: %v", err)
+	}
+
+	// 回放捕获文件，按原始时间回放时在后台进行，不阻塞Web服务启动
+	if *replayFile != "" {
+		if *replayRealtime {
+			go func() {
+				if _, err := monitor.ReplayCaptureFile(*replayFile, replayBase, true); err != nil {
+					log.Printf("回放捕获文件失败: %v", err)
+				}
+			}()
+		} else if _, err := monitor.ReplayCaptureFile(*replayFile, replayBase, false); err != nil {
+			log.Fatalf("回放捕获文件失败: %v", err)
+		}
+	}
+
+	r := api.InitRouter()
+	log.Printf("Web服务已启动: %s", *addr)
+	if err := r.Run(*addr); err != nil {
+		log.Fatalf("Web服务运行失败: %v", err)
+	}
+}
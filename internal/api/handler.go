@@ -1,8 +1,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -24,12 +30,14 @@ func InitRouter() *gin.Engine {
 	// 主页路由
 	r.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.tmpl", gin.H{
-			"title":          "文件访问监控",
-			"monitoring":     monitoringActive,
-			"includePattern": monitor.GetIncludePattern(),
-			"excludePattern": monitor.GetExcludePattern(),
-			"processPattern": monitor.GetProcessPattern(),
-			"storeStats":     database.GetStoreStats(),
+			"title":           "文件访问监控",
+			"monitoring":      monitoringActive,
+			"includePatterns": monitor.GetIncludePatterns(),
+			"excludePatterns": monitor.GetExcludePatterns(),
+			"processPatterns": monitor.GetProcessPatterns(),
+			"source":          monitor.GetEventSource(),
+			"command":         monitor.GetMonitorCommand(),
+			"storeStats":      database.GetStoreStats(),
 		})
 	})
 
@@ -45,32 +53,122 @@ func InitRouter() *gin.Engine {
 		// 启动监控
 		api.POST("/monitor/start", startMonitoring)
 
+		// 获取监控状态和当前会话的配置
+		api.GET("/monitor/status", getMonitorStatus)
+
 		// 停止监控
 		api.POST("/monitor/stop", stopMonitoring)
 
+		// 不停止监控替换包含目录、排除目录和进程通配符
+		api.PUT("/monitor/filters", updateMonitorFilters)
+
+		// 说明一条示例访问记录是否会被记录，以及由哪条规则决定
+		api.POST("/monitor/explain", explainAccess)
+
 		// 获取按时间范围过滤的访问记录
 		api.GET("/time-range", getAccessByTimeRange)
 
 		// 获取指定进程的文件访问记录
 		api.GET("/process-files", getProcessFiles)
 
+		// 获取指定PID的文件访问记录
+		api.GET("/pid-files", getPIDFiles)
+
 		// 获取按文件路径前缀筛选的访问记录
 		api.GET("/path-files", getFilesByPathPrefix)
 
+		// 获取删除记录，可按路径前缀筛选
+		api.GET("/deletions", getDeletions)
+
+		// 获取本次监控会话观察到的进程树
+		api.GET("/process-tree", getProcessTree)
+
+		// 获取进程表中指定PID的进程信息
+		api.GET("/processes/:pid", getProcessInfo)
+
+		// 获取按进程汇总的I/O统计
+		api.GET("/io/processes", getProcessIOSummary)
+
+		// 获取按文件汇总的I/O统计
+		api.GET("/io/files", getFileIOSummary)
+
 		// 获取内存存储统计信息
 		api.GET("/store/stats", getStoreStats)
 
 		// 设置内存存储的最大记录数
 		api.POST("/store/max-records", setMaxRecords)
+
+		// 上传并回放fs_usage捕获文件
+		api.POST("/replay", replayCapture)
+
+		// 获取fs_usage原始输出捕获文件列表
+		api.GET("/captures", getCaptureFiles)
+
+		// 下载fs_usage原始输出捕获文件
+		api.GET("/captures/:name", downloadCaptureFile)
+
+		// 获取所有的忽略规则集
+		api.GET("/ignore/profiles", getIgnoreProfiles)
+
+		// 获取指定的忽略规则集
+		api.GET("/ignore/profiles/:name", getIgnoreProfile)
+
+		// 创建或替换忽略规则集
+		api.PUT("/ignore/profiles/:name", saveIgnoreProfile)
+
+		// 删除忽略规则集，删除default时恢复内置规则
+		api.DELETE("/ignore/profiles/:name", deleteIgnoreProfile)
+
+		// 获取当前生效的忽略规则及被每条规则忽略的事件数
+		api.GET("/ignore/stats", getIgnoreStats)
+
+		// 获取进程的路径范围
+		api.GET("/process-scopes", getProcessScopes)
+
+		// 替换所有的进程路径范围
+		api.PUT("/process-scopes", setProcessScopes)
+
+		// 在末尾添加一个进程路径范围
+		api.POST("/process-scopes", addProcessScope)
+
+		// 删除指定序号的进程路径范围
+		api.DELETE("/process-scopes/:index", deleteProcessScope)
 	}
 
 	return r
 }
 
+// queryFilters 根据通用查询参数构建过滤条件
+// category: 按操作分类过滤，多个分类用逗号分隔，如 category=write,delete
+// filter: 过滤表达式，如 filter=process =~ "node*" && op in (write, rename)
+func queryFilters(c *gin.Context) ([]database.AccessFilter, error) {
+	var filters []database.AccessFilter
+
+	if categoryParam := c.Query("category"); categoryParam != "" {
+		filters = append(filters, database.CategoryFilter(strings.Split(categoryParam, ",")...))
+	}
+
+	if filterParam := strings.TrimSpace(c.Query("filter")); filterParam != "" {
+		filter, err := monitor.CompileFilter(filterParam)
+		if err != nil {
+			return nil, fmt.Errorf("过滤表达式无效: %w", err)
+		}
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
 // getRecentAccess 获取最近的文件访问记录
 func getRecentAccess(c *gin.Context) {
 	limit := 100 // 默认限制为100条记录
-	accesses, err := database.GetFileAccessList(limit)
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accesses, err := database.GetFileAccessList(limit, filters...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -78,9 +176,20 @@ func getRecentAccess(c *gin.Context) {
 	c.JSON(http.StatusOK, accesses)
 }
 
-// getAccessSummary 获取按进程分组的访问统计
+// getAccessSummary 获取按进程分组的访问统计，groupBy=pid时按进程名和PID分组
 func getAccessSummary(c *gin.Context) {
-	summary, err := database.GetAccessCountByProcess()
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var summary []database.FileAccessSummary
+	if c.Query("groupBy") == "pid" {
+		summary, err = database.GetAccessCountByPID(filters...)
+	} else {
+		summary, err = database.GetAccessCountByProcess(filters...)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -97,43 +206,118 @@ func startMonitoring(c *gin.Context) {
 
 	// 解析请求体，获取通配符参数
 	var request struct {
-		IncludePattern string `json:"includePattern"` // 包含目录通配符
-		ExcludePattern string `json:"excludePattern"` // 排除目录通配符
-		ProcessPattern string `json:"processPattern"` // 进程通配符
+		IncludePattern  string                 `json:"includePattern"`  // 包含目录通配符
+		ExcludePattern  string                 `json:"excludePattern"`  // 排除目录通配符
+		ProcessPattern  string                 `json:"processPattern"`  // 进程通配符
+		IncludePatterns []string               `json:"includePatterns"` // 多个包含目录通配符，匹配任意一个即记录
+		ExcludePatterns []string               `json:"excludePatterns"` // 多个排除目录通配符，匹配任意一个即排除
+		ProcessPatterns []string               `json:"processPatterns"` // 多个进程通配符，匹配任意一个即记录
+		Source          string                 `json:"source"`          // 事件源，如 fs_usage、fanotify
+		Strace          monitor.StraceOptions  `json:"strace"`          // strace事件源的命令、PID或输出文件
+		FSUsage         monitor.FSUsageOptions `json:"fsUsage"`         // fs_usage事件源的过滤模式和监控的进程
+		Capture         bool                   `json:"capture"`         // 是否保存fs_usage原始输出
+		Operations      []string               `json:"operations"`      // 记录的操作类型，如 open、unlink
+		Categories      []string               `json:"categories"`      // 记录的操作分类，如 metadata、delete
+		DeletionAudit   bool                   `json:"deletionAudit"`   // 删除审计模式
+		Ignore          monitor.IgnoreOptions  `json:"ignore"`          // 使用的忽略规则集及本次会话的调整
+		IgnoreFiles     []string               `json:"ignoreFiles"`     // gitignore文件或包含 .gitignore 的目录
+		Rules           []string               `json:"rules"`           // 规则列表，如 "- path:**/build/**"，第一条匹配的规则生效
+		ProcessScopes   []monitor.ProcessScope `json:"processScopes"`   // 进程的路径范围，未传入时使用之前设置的范围
+		Filter          string                 `json:"filter"`          // 过滤表达式，只记录满足表达式的访问
 		// 以下参数为了向后兼容保留
 		IncludeRegex string `json:"includeRegex"` // 旧的包含目录正则表达式
 		ExcludeRegex string `json:"excludeRegex"` // 旧的排除目录正则表达式
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		// 如果解析失败也不要报错，视为没有提供参数
-		request.IncludePattern = ""
-		request.ExcludePattern = ""
-		request.ProcessPattern = ""
+	// 没有请求体时使用默认设置，请求体无效时直接返回错误
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
 	}
 
-	// 如果新参数为空，尝试使用旧参数
+	// 如果新参数为空，尝试使用旧参数，旧参数按正则表达式处理
 	if request.IncludePattern == "" && request.IncludeRegex != "" {
-		request.IncludePattern = request.IncludeRegex
+		request.IncludePattern = "regex:" + request.IncludeRegex
 	}
 	if request.ExcludePattern == "" && request.ExcludeRegex != "" {
-		request.ExcludePattern = request.ExcludeRegex
+		request.ExcludePattern = "regex:" + request.ExcludeRegex
+	}
+
+	// 在启动事件源之前编译和校验全部设置，任意一项无效时直接返回错误，不修改当前的设置
+	// 单个模式与模式列表合并，未指定记录的操作时只记录默认的读写操作
+	session, err := monitor.PrepareSession(monitor.SessionOptions{
+		IncludePatterns: append(append([]string{}, request.IncludePatterns...), request.IncludePattern),
+		ExcludePatterns: append(append([]string{}, request.ExcludePatterns...), request.ExcludePattern),
+		ProcessPatterns: append(append([]string{}, request.ProcessPatterns...), request.ProcessPattern),
+		Rules:           request.Rules,
+		ProcessScopes:   request.ProcessScopes,
+		Filter:          request.Filter,
+		Ignore:          request.Ignore,
+		IgnoreFiles:     request.IgnoreFiles,
+		Operations:      request.Operations,
+		Categories:      request.Categories,
+		DeletionAudit:   request.DeletionAudit,
+		Source:          request.Source,
+		FSUsage:         request.FSUsage,
+		Strace:          request.Strace,
+		Capture:         request.Capture,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 在返回之前启动事件源，启动成功后才替换当前的设置，启动失败（如没有root权限）时不进入监控状态
+	source, err := session.Start()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, os.ErrPermission) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
 	}
 
 	// 创建一个通道用于停止监控
 	doneChan = make(chan bool)
 	monitoringActive = true
 
-	// 使用通配符匹配模式启动监控
-	go monitor.StartMonitoringWithWildcards(doneChan, request.IncludePattern, request.ExcludePattern, request.ProcessPattern)
+	go monitor.RunMonitoring(doneChan, source)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "已启动文件系统监控",
-		"command":        monitor.GetFSUsageCommand(),
-		"includePattern": request.IncludePattern,
-		"excludePattern": request.ExcludePattern,
-		"processPattern": request.ProcessPattern,
-	})
+	status := monitorStatus()
+	status["message"] = "已启动文件系统监控"
+	c.JSON(http.StatusOK, status)
+}
+
+// getMonitorStatus 获取监控状态和当前会话的配置
+func getMonitorStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, monitorStatus())
+}
+
+// monitorStatus 返回监控状态和当前会话生效的配置
+func monitorStatus() gin.H {
+	operations, categories := monitor.GetTrackedOperations()
+	return gin.H{
+		"active":          monitoringActive,
+		"command":         monitor.GetMonitorCommand(),
+		"source":          monitor.GetEventSource(),
+		"capture":         monitor.GetCaptureEnabled(),
+		"fsUsage":         monitor.GetFSUsageOptions(),
+		"operations":      operations,
+		"categories":      categories,
+		"deletionAudit":   monitor.GetDeletionAudit(),
+		"includePattern":  monitor.GetIncludePattern(),
+		"excludePattern":  monitor.GetExcludePattern(),
+		"processPattern":  monitor.GetProcessPattern(),
+		"includePatterns": monitor.GetIncludePatterns(),
+		"excludePatterns": monitor.GetExcludePatterns(),
+		"processPatterns": monitor.GetProcessPatterns(),
+		"filter":          monitor.GetFilterExpression(),
+		"ignore":          monitor.GetIgnoreOptions(),
+		"ignoreFiles":     monitor.GetIgnoreFiles(),
+		"rules":           monitor.GetFilterRules(),
+		"processScopes":   monitor.GetProcessScopes(),
+	}
 }
 
 // stopMonitoring 停止文件系统监控
@@ -149,12 +333,67 @@ func stopMonitoring(c *gin.Context) {
 
 	// 重置所有过滤条件
 	monitor.ResetPathPrefix()
-	monitor.ResetExcludeRegex()
+	monitor.ResetExcludePattern()
 	monitor.ResetProcessPattern()
+	monitor.ResetFilterRules()
+	monitor.ResetFilterExpression()
+	monitor.ResetIgnoreOptions()
+	monitor.ResetIgnoreFiles()
+	monitor.ResetEventSource()
+	monitor.ResetStraceOptions()
+	monitor.ResetFSUsageOptions()
+	monitor.ResetCapture()
+	monitor.ResetTrackedOperations()
+	monitor.ResetDeletionAudit()
 
 	c.JSON(http.StatusOK, gin.H{"message": "已停止文件系统监控"})
 }
 
+// updateMonitorFilters 替换运行中的会话的包含目录、排除目录和进程通配符，不需要停止事件源
+// 请求体中未传入的列表保持不变，传入空列表表示清空
+func updateMonitorFilters(c *gin.Context) {
+	if !monitoringActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "监控未在运行"})
+		return
+	}
+
+	var patterns monitor.FilterPatterns
+	if err := c.ShouldBindJSON(&patterns); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供有效的includePatterns、excludePatterns或processPatterns参数"})
+		return
+	}
+
+	// fanotify和inotify监控的目录在启动时根据包含目录通配符确定，超出这些目录时需要重新启动监控
+	if err := monitor.ReplacePatterns(patterns); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, monitor.ErrRootsChanged) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := monitorStatus()
+	status["message"] = "已更新过滤条件"
+	c.JSON(http.StatusOK, status)
+}
+
+// explainAccess 说明一条示例访问记录在当前会话的配置下是否会被记录，以及由哪个环节的哪条规则决定
+// 请求体使用访问记录的字段，如 {"process_name": "node", "file_path": "/src/build/a.js", "operation": "open"}
+func explainAccess(c *gin.Context) {
+	var access database.FileAccess
+	if err := c.ShouldBindJSON(&access); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供有效的访问记录"})
+		return
+	}
+	if access.FilePath == "" && access.ProcessName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少file_path或process_name参数"})
+		return
+	}
+
+	c.JSON(http.StatusOK, monitor.ExplainAccess(access))
+}
+
 // getAccessByTimeRange 获取指定时间范围内的访问记录
 func getAccessByTimeRange(c *gin.Context) {
 	// 默认值为过去24小时
@@ -177,8 +416,14 @@ func getAccessByTimeRange(c *gin.Context) {
 		}
 	}
 
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 获取记录
-	accesses, err := database.GetRecentAccessByTimeRange(startTime, endTime)
+	accesses, err := database.GetRecentAccessByTimeRange(startTime, endTime, filters...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -203,7 +448,44 @@ func getProcessFiles(c *gin.Context) {
 		}
 	}
 
-	accesses, err := database.GetAccessByProcessName(processName, limit)
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accesses, err := database.GetAccessByProcessName(processName, limit, filters...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, accesses)
+}
+
+// getPIDFiles 获取指定PID的文件访问记录
+func getPIDFiles(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Query("pid"))
+	if err != nil || pid <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少有效的PID参数"})
+		return
+	}
+
+	limit := 100 // 默认限制为100条记录
+	limitParam := c.Query("limit")
+	if limitParam != "" {
+		if n, err := strconv.Atoi(limitParam); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accesses, err := database.GetAccessByPID(pid, limit, filters...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -228,7 +510,13 @@ func getFilesByPathPrefix(c *gin.Context) {
 		}
 	}
 
-	accesses, err := database.GetAccessByPathPrefix(pathPrefix, limit)
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accesses, err := database.GetAccessByPathPrefix(pathPrefix, limit, filters...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -237,6 +525,101 @@ func getFilesByPathPrefix(c *gin.Context) {
 	c.JSON(http.StatusOK, accesses)
 }
 
+// getDeletions 获取删除记录，包括删除的路径、执行删除的进程、PID和时间
+func getDeletions(c *gin.Context) {
+	limit := 100 // 默认限制为100条记录
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletions, err := database.GetDeletions(c.Query("prefix"), limit, filters...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deletions)
+}
+
+// getProcessTree 获取本次监控会话观察到的进程树，每个节点带有该进程的文件访问次数
+func getProcessTree(c *gin.Context) {
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tree, err := database.GetProcessTree(filters...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// getProcessInfo 获取进程的父进程、程序路径、命令行和用户等信息
+func getProcessInfo(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil || pid <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的PID参数"})
+		return
+	}
+
+	info, ok := database.GetProcess(pid)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "进程不在本次监控会话的进程表中"})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// getProcessIOSummary 获取按进程汇总的读写字节数、I/O耗时和错误次数
+func getProcessIOSummary(c *gin.Context) {
+	limit := 100 // 默认限制为100条记录
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := database.GetIOSummaryByProcess(limit, filters...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// getFileIOSummary 获取按文件汇总的读写字节数、I/O耗时和错误次数
+func getFileIOSummary(c *gin.Context) {
+	limit := 100 // 默认限制为100条记录
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	filters, err := queryFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := database.GetIOSummaryByFile(limit, filters...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
 // getStoreStats 获取内存存储的统计信息
 func getStoreStats(c *gin.Context) {
 	stats := database.GetStoreStats()
@@ -266,3 +649,187 @@ func setMaxRecords(c *gin.Context) {
 		"stats":   database.GetStoreStats(),
 	})
 }
+
+// replayCapture 上传fs_usage捕获文件并回放
+// 默认尽快回放并返回写入的记录数，realtime=true时按原始时间间隔在后台回放
+// 捕获文件没有记录开始时间时需要通过date指定捕获开始的日期（YYYY-MM-DD）
+func replayCapture(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传捕获文件"})
+		return
+	}
+
+	realtime, _ := strconv.ParseBool(c.PostForm("realtime"))
+
+	var base time.Time
+	if dateParam := c.PostForm("date"); dateParam != "" {
+		t, err := time.ParseInLocation("2006-01-02", dateParam, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date格式应为YYYY-MM-DD"})
+			return
+		}
+		base = t
+	}
+
+	// 保存到临时文件，后台回放时上传的文件会在请求结束后被删除
+	tmp, err := os.CreateTemp("", "filewatch-replay-*.txt")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tmp.Close()
+
+	if err := c.SaveUploadedFile(file, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := monitor.CaptureStartTime(tmp.Name()); !ok && base.IsZero() {
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusBadRequest, gin.H{"error": monitor.ErrCaptureStartUnknown.Error()})
+		return
+	}
+
+	if realtime {
+		go func() {
+			defer os.Remove(tmp.Name())
+			if _, err := monitor.ReplayCaptureFile(tmp.Name(), base, true); err != nil {
+				log.Printf("回放捕获文件失败: %v", err)
+			}
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "已开始按原始时间回放捕获文件"})
+		return
+	}
+
+	defer os.Remove(tmp.Name())
+	count, err := monitor.ReplayCaptureFile(tmp.Name(), base, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "count": count})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "捕获文件回放完成",
+		"count":   count,
+	})
+}
+
+// getCaptureFiles 获取fs_usage原始输出捕获文件列表
+func getCaptureFiles(c *gin.Context) {
+	files, err := monitor.ListCaptureFiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// downloadCaptureFile 下载fs_usage原始输出捕获文件
+func downloadCaptureFile(c *gin.Context) {
+	name := c.Param("name")
+	path, err := monitor.GetCaptureFilePath(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.FileAttachment(path, name)
+}
+
+// getIgnoreProfiles 获取所有的忽略规则集
+func getIgnoreProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, monitor.GetIgnoreProfiles())
+}
+
+// getIgnoreProfile 获取指定的忽略规则集
+func getIgnoreProfile(c *gin.Context) {
+	profile, ok := monitor.GetIgnoreProfile(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "忽略规则集不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// saveIgnoreProfile 创建或替换忽略规则集，当前会话使用该规则集时立即生效
+func saveIgnoreProfile(c *gin.Context) {
+	var profile monitor.IgnoreProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供有效的prefixes和extensions参数"})
+		return
+	}
+	profile.Name = c.Param("name")
+
+	saved, err := monitor.SaveIgnoreProfile(profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// deleteIgnoreProfile 删除忽略规则集，删除default时恢复内置规则
+func deleteIgnoreProfile(c *gin.Context) {
+	if err := monitor.DeleteIgnoreProfile(c.Param("name")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已删除忽略规则集"})
+}
+
+// getIgnoreStats 获取当前生效的忽略规则及本次会话中被每条规则忽略的事件数
+func getIgnoreStats(c *gin.Context) {
+	c.JSON(http.StatusOK, monitor.GetIgnoreStats())
+}
+
+// getProcessScopes 获取进程的路径范围
+func getProcessScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, monitor.GetProcessScopes())
+}
+
+// setProcessScopes 替换所有的进程路径范围，监控运行时立即生效
+func setProcessScopes(c *gin.Context) {
+	var scopes []monitor.ProcessScope
+	if err := c.ShouldBindJSON(&scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供有效的进程路径范围列表"})
+		return
+	}
+
+	if err := monitor.SetProcessScopes(scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, monitor.GetProcessScopes())
+}
+
+// addProcessScope 在末尾添加一个进程路径范围，监控运行时立即生效
+func addProcessScope(c *gin.Context) {
+	var scope monitor.ProcessScope
+	if err := c.ShouldBindJSON(&scope); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供有效的进程路径范围"})
+		return
+	}
+
+	if err := monitor.AddProcessScope(scope); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, monitor.GetProcessScopes())
+}
+
+// deleteProcessScope 删除指定序号（从1开始）的进程路径范围
+func deleteProcessScope(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的序号参数"})
+		return
+	}
+
+	if err := monitor.DeleteProcessScope(index); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, monitor.GetProcessScopes())
+}
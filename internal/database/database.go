@@ -72,35 +72,33 @@ func AddFileAccessBatch(accesses []FileAccess) error {
 }
 
 // GetFileAccessList 获取最近的文件访问记录
-func GetFileAccessList(limit int) ([]FileAccess, error) {
+func GetFileAccessList(limit int, filters ...AccessFilter) ([]FileAccess, error) {
 	Store.mu.RLock()
 	defer Store.mu.RUnlock()
 
 	result := make([]FileAccess, 0, limit)
 
-	// 复制最新的记录
-	totalRecords := len(Store.accesses)
-	startIdx := totalRecords - limit
-	if startIdx < 0 {
-		startIdx = 0
-	}
-
-	// 按时间降序返回
-	for i := totalRecords - 1; i >= startIdx; i-- {
-		result = append(result, Store.accesses[i])
+	// 按时间降序返回最新的记录
+	for i := len(Store.accesses) - 1; i >= 0 && len(result) < limit; i-- {
+		if matchFilters(Store.accesses[i], filters) {
+			result = append(result, Store.accesses[i])
+		}
 	}
 
 	return result, nil
 }
 
 // GetAccessCountByProcess 获取各进程访问文件的次数统计
-func GetAccessCountByProcess() ([]FileAccessSummary, error) {
+func GetAccessCountByProcess(filters ...AccessFilter) ([]FileAccessSummary, error) {
 	Store.mu.RLock()
 	defer Store.mu.RUnlock()
 
 	// 使用map统计每个进程的访问次数
 	countMap := make(map[string]int)
 	for _, access := range Store.accesses {
+		if !matchFilters(access, filters) {
+			continue
+		}
 		countMap[access.ProcessName]++
 	}
 
@@ -121,8 +119,45 @@ func GetAccessCountByProcess() ([]FileAccessSummary, error) {
 	return result, nil
 }
 
+// GetAccessCountByPID 获取各进程实例（进程名+PID）访问文件的次数统计
+func GetAccessCountByPID(filters ...AccessFilter) ([]FileAccessSummary, error) {
+	Store.mu.RLock()
+	defer Store.mu.RUnlock()
+
+	type processKey struct {
+		name string
+		pid  int
+	}
+
+	// 使用map统计每个进程实例的访问次数
+	countMap := make(map[processKey]int)
+	for _, access := range Store.accesses {
+		if !matchFilters(access, filters) {
+			continue
+		}
+		countMap[processKey{access.ProcessName, access.PID}]++
+	}
+
+	// 转换为切片并排序
+	result := make([]FileAccessSummary, 0, len(countMap))
+	for key, count := range countMap {
+		result = append(result, FileAccessSummary{
+			ProcessName: key.name,
+			PID:         key.pid,
+			Count:       count,
+		})
+	}
+
+	// 按访问次数降序排序
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+
+	return result, nil
+}
+
 // GetRecentAccessByTimeRange 获取指定时间范围内的访问记录
-func GetRecentAccessByTimeRange(start, end time.Time) ([]FileAccess, error) {
+func GetRecentAccessByTimeRange(start, end time.Time, filters ...AccessFilter) ([]FileAccess, error) {
 	Store.mu.RLock()
 	defer Store.mu.RUnlock()
 
@@ -131,7 +166,7 @@ func GetRecentAccessByTimeRange(start, end time.Time) ([]FileAccess, error) {
 	// 筛选时间范围内的记录
 	for i := len(Store.accesses) - 1; i >= 0; i-- {
 		access := Store.accesses[i]
-		if access.Timestamp.After(start) && access.Timestamp.Before(end) {
+		if access.Timestamp.After(start) && access.Timestamp.Before(end) && matchFilters(access, filters) {
 			result = append(result, access)
 		}
 	}
@@ -140,7 +175,7 @@ func GetRecentAccessByTimeRange(start, end time.Time) ([]FileAccess, error) {
 }
 
 // GetAccessByProcessName 获取指定进程的文件访问记录
-func GetAccessByProcessName(processName string, limit int) ([]FileAccess, error) {
+func GetAccessByProcessName(processName string, limit int, filters ...AccessFilter) ([]FileAccess, error) {
 	Store.mu.RLock()
 	defer Store.mu.RUnlock()
 
@@ -149,7 +184,7 @@ func GetAccessByProcessName(processName string, limit int) ([]FileAccess, error)
 
 	// 从最新记录开始，筛选指定进程名的记录
 	for i := len(Store.accesses) - 1; i >= 0 && count < limit; i-- {
-		if Store.accesses[i].ProcessName == processName {
+		if Store.accesses[i].ProcessName == processName && matchFilters(Store.accesses[i], filters) {
 			result = append(result, Store.accesses[i])
 			count++
 		}
@@ -158,8 +193,27 @@ func GetAccessByProcessName(processName string, limit int) ([]FileAccess, error)
 	return result, nil
 }
 
-// GetAccessByPathPrefix 获取指定路径前缀的文件访问记录
-func GetAccessByPathPrefix(pathPrefix string, limit int) ([]FileAccess, error) {
+// GetAccessByPID 获取指定PID的文件访问记录
+func GetAccessByPID(pid int, limit int, filters ...AccessFilter) ([]FileAccess, error) {
+	Store.mu.RLock()
+	defer Store.mu.RUnlock()
+
+	result := make([]FileAccess, 0, limit)
+	count := 0
+
+	// 从最新记录开始，筛选指定PID的记录
+	for i := len(Store.accesses) - 1; i >= 0 && count < limit; i-- {
+		if Store.accesses[i].PID == pid && matchFilters(Store.accesses[i], filters) {
+			result = append(result, Store.accesses[i])
+			count++
+		}
+	}
+
+	return result, nil
+}
+
+// GetAccessByPathPrefix 获取指定路径前缀的文件访问记录，源路径或目标路径匹配均可
+func GetAccessByPathPrefix(pathPrefix string, limit int, filters ...AccessFilter) ([]FileAccess, error) {
 	Store.mu.RLock()
 	defer Store.mu.RUnlock()
 
@@ -168,7 +222,7 @@ func GetAccessByPathPrefix(pathPrefix string, limit int) ([]FileAccess, error) {
 
 	// 从最新记录开始，筛选匹配路径前缀的记录
 	for i := len(Store.accesses) - 1; i >= 0 && count < limit; i-- {
-		if strings.HasPrefix(Store.accesses[i].FilePath, pathPrefix) {
+		if matchPathPrefix(Store.accesses[i], pathPrefix) && matchFilters(Store.accesses[i], filters) {
 			result = append(result, Store.accesses[i])
 			count++
 		}
@@ -177,6 +231,106 @@ func GetAccessByPathPrefix(pathPrefix string, limit int) ([]FileAccess, error) {
 	return result, nil
 }
 
+// GetDeletions 获取删除记录，pathPrefix为空时返回所有删除记录
+// 源路径或目标路径匹配前缀均可
+func GetDeletions(pathPrefix string, limit int, filters ...AccessFilter) ([]FileAccess, error) {
+	Store.mu.RLock()
+	defer Store.mu.RUnlock()
+
+	result := make([]FileAccess, 0, limit)
+
+	// 从最新记录开始，筛选删除操作
+	for i := len(Store.accesses) - 1; i >= 0 && len(result) < limit; i-- {
+		access := Store.accesses[i]
+		if access.Category != CategoryDelete {
+			continue
+		}
+		if pathPrefix != "" && !matchPathPrefix(access, pathPrefix) {
+			continue
+		}
+		if matchFilters(access, filters) {
+			result = append(result, access)
+		}
+	}
+
+	return result, nil
+}
+
+// GetIOSummaryByProcess 获取各进程的I/O统计，按读写字节总数降序排列
+func GetIOSummaryByProcess(limit int, filters ...AccessFilter) ([]IOSummary, error) {
+	return getIOSummary(limit, filters, func(access FileAccess) IOSummary {
+		return IOSummary{ProcessName: access.ProcessName}
+	})
+}
+
+// GetIOSummaryByFile 获取各文件的I/O统计，按读写字节总数降序排列
+func GetIOSummaryByFile(limit int, filters ...AccessFilter) ([]IOSummary, error) {
+	return getIOSummary(limit, filters, func(access FileAccess) IOSummary {
+		return IOSummary{FilePath: access.FilePath}
+	})
+}
+
+// getIOSummary 按keyOf返回的分组键汇总I/O统计
+func getIOSummary(limit int, filters []AccessFilter, keyOf func(FileAccess) IOSummary) ([]IOSummary, error) {
+	Store.mu.RLock()
+	defer Store.mu.RUnlock()
+
+	summaryMap := make(map[IOSummary]*IOSummary)
+	for _, access := range Store.accesses {
+		if !matchFilters(access, filters) {
+			continue
+		}
+
+		key := keyOf(access)
+		summary, ok := summaryMap[key]
+		if !ok {
+			summary = &IOSummary{ProcessName: key.ProcessName, FilePath: key.FilePath}
+			summaryMap[key] = summary
+		}
+
+		summary.Count++
+		summary.IOWait += access.Duration
+		if access.Errno != 0 {
+			summary.ErrorCount++
+		}
+
+		switch access.Category {
+		case CategoryWrite:
+			summary.BytesWritten += access.Bytes
+		case CategoryRead:
+			summary.BytesRead += access.Bytes
+		}
+	}
+
+	result := make([]IOSummary, 0, len(summaryMap))
+	for _, summary := range summaryMap {
+		result = append(result, *summary)
+	}
+
+	// 按读写字节总数降序排序，字节数相同时按耗时排序
+	sort.Slice(result, func(i, j int) bool {
+		bytesI := result[i].BytesRead + result[i].BytesWritten
+		bytesJ := result[j].BytesRead + result[j].BytesWritten
+		if bytesI != bytesJ {
+			return bytesI > bytesJ
+		}
+		return result[i].IOWait > result[j].IOWait
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// matchPathPrefix 判断访问记录的源路径或目标路径是否以指定前缀开头
+func matchPathPrefix(access FileAccess, pathPrefix string) bool {
+	if strings.HasPrefix(access.FilePath, pathPrefix) {
+		return true
+	}
+	return access.TargetPath != "" && strings.HasPrefix(access.TargetPath, pathPrefix)
+}
+
 // SetMaxRecords 设置存储的最大记录数
 func SetMaxRecords(maxRecords int) {
 	if maxRecords <= 0 {
@@ -11,22 +11,96 @@ type FileAccess struct {
 	CreatedAt   time.Time `json:"created_at"`
 	Timestamp   time.Time `json:"timestamp"`
 	ProcessName string    `json:"process_name"`
+	PID         int       `json:"pid"`            // 进程ID，0表示未知
+	PPID        int       `json:"ppid,omitempty"` // 父进程ID，0表示未知
+	TID         int       `json:"tid,omitempty"`  // 线程ID，部分事件源无法获取
 	FilePath    string    `json:"file_path"`
+	TargetPath  string    `json:"target_path,omitempty"` // 重命名、链接等操作的目标路径
 	Operation   string    `json:"operation"`
+	Category    string    `json:"category"`         // 操作分类，见Category常量
+	Mode        string    `json:"mode,omitempty"`   // 产生事件的fs_usage过滤模式，如 filesystem、diskio
+	IsDir       bool      `json:"is_dir,omitempty"` // 路径为目录，部分事件源无法区分
+	// 以下字段取决于事件源是否提供，0表示未知
+	Duration time.Duration `json:"duration,omitempty"` // 系统调用耗时（纳秒）
+	Bytes    int64         `json:"bytes,omitempty"`    // 读写的字节数
+	FD       int           `json:"fd,omitempty"`       // 文件描述符
+	Errno    int           `json:"errno,omitempty"`    // 系统调用失败时的错误码
+}
+
+// 操作分类
+const (
+	CategoryRead     = "read"
+	CategoryWrite    = "write"
+	CategoryCreate   = "create"
+	CategoryDelete   = "delete"
+	CategoryMetadata = "metadata"
+	CategoryExec     = "exec"
+	CategoryOther    = "other"
+)
+
+// Categories 所有的操作分类
+var Categories = []string{
+	CategoryRead,
+	CategoryWrite,
+	CategoryCreate,
+	CategoryDelete,
+	CategoryMetadata,
+	CategoryExec,
+	CategoryOther,
+}
+
+// AccessFilter 查询时对访问记录的过滤条件，返回true表示保留
+type AccessFilter func(FileAccess) bool
+
+// CategoryFilter 只保留指定分类的访问记录，未指定分类时不过滤
+func CategoryFilter(categories ...string) AccessFilter {
+	allowed := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		allowed[category] = true
+	}
+
+	return func(access FileAccess) bool {
+		return len(allowed) == 0 || allowed[access.Category]
+	}
+}
+
+// matchFilters 判断访问记录是否满足所有过滤条件
+func matchFilters(access FileAccess, filters []AccessFilter) bool {
+	for _, filter := range filters {
+		if !filter(access) {
+			return false
+		}
+	}
+	return true
 }
 
 // FileAccessSummary 表示文件访问统计信息
 type FileAccessSummary struct {
 	ProcessName string `json:"process_name"`
+	PID         int    `json:"pid,omitempty"` // 按PID分组统计时有效
 	Count       int    `json:"count"`
 }
 
+// IOSummary 表示按进程或文件汇总的I/O统计信息
+type IOSummary struct {
+	ProcessName  string        `json:"process_name,omitempty"`
+	FilePath     string        `json:"file_path,omitempty"`
+	Count        int           `json:"count"`
+	BytesRead    int64         `json:"bytes_read"`
+	BytesWritten int64         `json:"bytes_written"`
+	IOWait       time.Duration `json:"io_wait"` // 系统调用总耗时（纳秒）
+	ErrorCount   int           `json:"error_count"`
+}
+
 // MemoryStore 内存存储结构
 type MemoryStore struct {
 	accesses   []FileAccess
 	mu         sync.RWMutex
 	maxRecords int
 	currentID  uint
+	// 进程表，记录监控期间观察到的进程
+	processes      map[int]*ProcessInfo
+	processesSince time.Time
 }
 
 // NewMemoryStore 创建新的内存存储
@@ -38,5 +112,6 @@ func NewMemoryStore(maxRecords int) *MemoryStore {
 		accesses:   make([]FileAccess, 0, maxRecords/2),
 		maxRecords: maxRecords,
 		currentID:  1,
+		processes:  make(map[int]*ProcessInfo),
 	}
 }
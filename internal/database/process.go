@@ -0,0 +1,128 @@
+package database
+
+import (
+	"sort"
+	"time"
+)
+
+// maxProcesses 进程表最多保存的进程数，超出后清理已退出的进程
+const maxProcesses = 50000
+
+// ProcessInfo 表示监控期间观察到的进程
+type ProcessInfo struct {
+	PID       int        `json:"pid"`
+	PPID      int        `json:"ppid"` // 父进程PID，0表示未知
+	Name      string     `json:"name"`
+	ExecPath  string     `json:"exec_path,omitempty"` // 最近一次exec的程序路径
+	Cmdline   string     `json:"cmdline,omitempty"`   // 命令行，参数之间以空格分隔
+	User      string     `json:"user,omitempty"`      // 运行进程的用户
+	StartedAt time.Time  `json:"started_at"`          // fork或exec的时间，未观察到时为第一次出现的时间
+	ExitedAt  *time.Time `json:"exited_at,omitempty"` // 进程退出的时间，未观察到退出时为空
+}
+
+// ProcessNode 表示进程树中的一个节点
+type ProcessNode struct {
+	ProcessInfo
+	AccessCount int            `json:"access_count"` // 该进程自身的文件访问次数，不含子进程
+	Children    []*ProcessNode `json:"children,omitempty"`
+}
+
+// GetProcess 获取进程表中的进程信息
+func GetProcess(pid int) (ProcessInfo, bool) {
+	Store.mu.RLock()
+	defer Store.mu.RUnlock()
+
+	info, ok := Store.processes[pid]
+	if !ok {
+		return ProcessInfo{}, false
+	}
+	return *info, true
+}
+
+// SaveProcess 保存进程信息，相同PID的进程会被覆盖
+func SaveProcess(info ProcessInfo) {
+	Store.mu.Lock()
+	defer Store.mu.Unlock()
+
+	if _, exists := Store.processes[info.PID]; !exists && len(Store.processes) >= maxProcesses {
+		for pid, process := range Store.processes {
+			if process.ExitedAt != nil {
+				delete(Store.processes, pid)
+			}
+		}
+	}
+
+	Store.processes[info.PID] = &info
+}
+
+// ClearProcesses 清空进程表，开始新的监控会话时调用
+func ClearProcesses() {
+	Store.mu.Lock()
+	defer Store.mu.Unlock()
+
+	Store.processes = make(map[int]*ProcessInfo)
+	Store.processesSince = time.Now()
+}
+
+// GetProcessTree 获取本次会话观察到的进程树，父进程不在进程表中的进程作为根节点
+// 访问次数只统计进程表清空之后写入的访问记录
+func GetProcessTree(filters ...AccessFilter) ([]*ProcessNode, error) {
+	Store.mu.RLock()
+	defer Store.mu.RUnlock()
+
+	nodes := make(map[int]*ProcessNode, len(Store.processes))
+	for pid, info := range Store.processes {
+		nodes[pid] = &ProcessNode{ProcessInfo: *info}
+	}
+
+	for _, access := range Store.accesses {
+		if access.CreatedAt.Before(Store.processesSince) || !matchFilters(access, filters) {
+			continue
+		}
+		if node, ok := nodes[access.PID]; ok {
+			node.AccessCount++
+		}
+	}
+
+	var roots []*ProcessNode
+	for _, node := range nodes {
+		parent, ok := nodes[node.PPID]
+		if !ok || node.PPID == node.PID || isProcessAncestor(nodes, node.PID, node.PPID) {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	sortProcessNodes(roots)
+	return roots, nil
+}
+
+// isProcessAncestor 判断pid是否为parent的祖先进程，PID复用时可能出现环
+func isProcessAncestor(nodes map[int]*ProcessNode, pid int, parent int) bool {
+	for depth := 0; depth < len(nodes); depth++ {
+		node, ok := nodes[parent]
+		if !ok || node.PPID == parent {
+			return false
+		}
+		if node.PPID == pid {
+			return true
+		}
+		parent = node.PPID
+	}
+	return true
+}
+
+// sortProcessNodes 按启动时间和PID对节点及其子节点排序
+func sortProcessNodes(nodes []*ProcessNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if !nodes[i].StartedAt.Equal(nodes[j].StartedAt) {
+			return nodes[i].StartedAt.Before(nodes[j].StartedAt)
+		}
+		return nodes[i].PID < nodes[j].PID
+	})
+
+	for _, node := range nodes {
+		sortProcessNodes(node.Children)
+	}
+}
@@ -0,0 +1,270 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 原始输出捕获文件的轮转参数
+const (
+	captureMaxFileSize = 50 * 1024 * 1024
+	captureMaxLineSize = 1024 * 1024 // 超过该长度仍没有换行时直接写入，避免缓冲无限增长
+	captureMaxFiles    = 20
+	capturePrefix      = "fs_usage-"
+	captureSuffix      = ".txt"
+
+	// captureHeaderPrefix 捕获文件第一行记录文件开始的时间，fs_usage只输出时刻，回放时以此还原日期
+	captureHeaderPrefix = "# filewatch-capture-start: "
+	// captureHeaderMaxTime 开始时间（RFC3339Nano格式）的最大长度，含换行符
+	captureHeaderMaxTime = 40
+)
+
+// 全局变量，用于存储是否保存fs_usage原始输出
+var captureEnabled bool
+
+// 全局变量，用于存储捕获文件所在目录
+var captureDir = filepath.Join(os.TempDir(), "filewatch-captures")
+
+// CaptureFile 捕获文件信息
+type CaptureFile struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// rotatingWriter 按大小轮转的捕获文件写入器
+// 只在换行处切换文件，每个捕获文件都只包含完整的行，可以单独回放
+// 写入失败只记录日志并停止捕获，不影响对输出的解析
+type rotatingWriter struct {
+	mu      sync.Mutex
+	session string
+	index   int
+	file    *os.File
+	size    int64
+	maxSize int64
+	pending []byte // 尚未遇到换行的不完整行
+	failed  bool
+}
+
+// newRotatingWriter 为一次监控会话创建捕获文件写入器
+func newRotatingWriter() (*rotatingWriter, error) {
+	if err := os.MkdirAll(captureDir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建捕获目录失败: %w", err)
+	}
+
+	w := &rotatingWriter{
+		session: time.Now().Format("20060102-150405"),
+		maxSize: captureMaxFileSize,
+	}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 写入原始输出，不完整的行先缓存，超过单个文件大小时在换行处切换到新文件
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.failed || w.file == nil {
+		return len(p), nil
+	}
+
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexByte(w.pending, '\n') + 1
+	if end == 0 && len(w.pending) >= captureMaxLineSize {
+		end = len(w.pending)
+	}
+
+	for written := 0; written < end && !w.failed; {
+		chunk := w.pending[written:end]
+
+		// 放不下时只写入能放下的完整行，剩余的行写入新文件
+		if w.size+int64(len(chunk)) > w.maxSize {
+			fit := 0
+			if room := w.maxSize - w.size; room > 0 {
+				fit = bytes.LastIndexByte(chunk[:room], '\n') + 1
+			}
+			if fit == 0 && w.size > 0 {
+				if err := w.rotate(); err != nil {
+					w.fail(err)
+				}
+				continue
+			}
+			if fit == 0 {
+				// 单独一行超过文件大小时单独写入一个文件
+				fit = bytes.IndexByte(chunk, '\n') + 1
+			}
+			if fit > 0 {
+				chunk = chunk[:fit]
+			}
+		}
+
+		n, err := w.file.Write(chunk)
+		w.size += int64(n)
+		written += n
+		if err != nil {
+			w.fail(err)
+		}
+	}
+
+	w.pending = append(w.pending[:0], w.pending[end:]...)
+	return len(p), nil
+}
+
+// rotate 关闭当前文件并打开新文件，同时清理超出数量限制的旧文件
+func (w *rotatingWriter) rotate() error {
+	if w.file != nil {
+		w.file.Close()
+	}
+
+	w.index++
+	name := fmt.Sprintf("%s%s.%03d%s", capturePrefix, w.session, w.index, captureSuffix)
+	file, err := os.Create(filepath.Join(captureDir, name))
+	if err != nil {
+		w.file = nil
+		return fmt.Errorf("创建捕获文件失败: %w", err)
+	}
+
+	w.file = file
+	w.size = 0
+	log.Printf("fs_usage原始输出写入: %s", file.Name())
+
+	// 开始时间行不计入文件大小，文件中只有开始时间行时任意一行都能写入
+	if _, err := file.WriteString(captureHeaderPrefix + time.Now().Format(time.RFC3339Nano) + "\n"); err != nil {
+		return fmt.Errorf("写入捕获文件失败: %w", err)
+	}
+
+	removeOldCaptures()
+	return nil
+}
+
+// fail 记录写入错误并停止捕获
+func (w *rotatingWriter) fail(err error) {
+	log.Printf("写入捕获文件失败，停止保存原始输出: %v", err)
+	w.failed = true
+}
+
+// Close 关闭当前捕获文件
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	// 输出结束时最后一行可能没有换行
+	if len(w.pending) > 0 && !w.failed {
+		n, _ := w.file.Write(w.pending)
+		w.size += int64(n)
+		w.pending = nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// parseCaptureHeader 解析捕获文件第一行记录的开始时间，不是开始时间行时返回false
+func parseCaptureHeader(line string) (time.Time, bool) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(line), captureHeaderPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.Local(), true
+}
+
+// removeOldCaptures 只保留最新的captureMaxFiles个捕获文件
+func removeOldCaptures() {
+	files, err := ListCaptureFiles()
+	if err != nil || len(files) <= captureMaxFiles {
+		return
+	}
+
+	for _, file := range files[captureMaxFiles:] {
+		os.Remove(filepath.Join(captureDir, file.Name))
+	}
+}
+
+// ListCaptureFiles 列出所有捕获文件，最新的在前
+func ListCaptureFiles() ([]CaptureFile, error) {
+	entries, err := os.ReadDir(captureDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []CaptureFile{}, nil
+		}
+		return nil, err
+	}
+
+	files := make([]CaptureFile, 0, len(entries))
+	for _, entry := range entries {
+		if !isCaptureFileName(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, CaptureFile{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			UpdatedAt: info.ModTime(),
+		})
+	}
+
+	// 文件名包含会话时间和序号，按名称降序即为最新的在前
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name > files[j].Name
+	})
+	return files, nil
+}
+
+// GetCaptureFilePath 返回捕获文件的完整路径，只允许访问捕获目录中的捕获文件
+func GetCaptureFilePath(name string) (string, error) {
+	if filepath.Base(name) != name || !isCaptureFileName(name) {
+		return "", fmt.Errorf("无效的捕获文件名: %s", name)
+	}
+
+	path := filepath.Join(captureDir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("捕获文件不存在: %s", name)
+	}
+	return path, nil
+}
+
+// isCaptureFileName 判断是否为捕获文件名
+func isCaptureFileName(name string) bool {
+	return strings.HasPrefix(name, capturePrefix) && strings.HasSuffix(name, captureSuffix)
+}
+
+// SetCaptureEnabled 设置是否在监控期间保存fs_usage原始输出
+func SetCaptureEnabled(enabled bool) {
+	captureEnabled = enabled
+	if enabled {
+		log.Printf("已开启fs_usage原始输出捕获，保存目录: %s", captureDir)
+	}
+}
+
+// GetCaptureEnabled 获取是否保存fs_usage原始输出
+func GetCaptureEnabled() bool {
+	return captureEnabled
+}
+
+// ResetCapture 关闭fs_usage原始输出捕获
+func ResetCapture() {
+	captureEnabled = false
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriterSplitsAtLineBoundary(t *testing.T) {
+	oldDir := captureDir
+	captureDir = t.TempDir()
+	defer func() { captureDir = oldDir }()
+
+	w, err := newRotatingWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxSize = 64
+
+	var input bytes.Buffer
+	for i := 0; i < 20; i++ {
+		input.WriteString("12:00:00.000100  open  /Users/me/file" + strings.Repeat("x", i) + "\n")
+	}
+	input.WriteString("tail without newline")
+
+	// 按不对齐行的大小分块写入
+	data := input.Bytes()
+	for len(data) > 0 {
+		n := min(7, len(data))
+		w.Write(data[:n])
+		data = data[n:]
+	}
+	w.Close()
+
+	files, err := ListCaptureFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("got %d capture files, want rotation", len(files))
+	}
+
+	var joined bytes.Buffer
+	for i := len(files) - 1; i >= 0; i-- {
+		content, err := os.ReadFile(filepath.Join(captureDir, files[i].Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			t.Errorf("%s does not end at a line boundary", files[i].Name)
+		}
+
+		header, body, _ := bytes.Cut(content, []byte("\n"))
+		if _, ok := parseCaptureHeader(string(header)); !ok {
+			t.Errorf("%s does not start with a capture header: %q", files[i].Name, header)
+		}
+		joined.Write(body)
+	}
+	if joined.String() != input.String() {
+		t.Errorf("captured content differs from input:\n%q\n%q", joined.String(), input.String())
+	}
+}
+
+func TestCaptureHeaderAnchorsClock(t *testing.T) {
+	start := time.Date(2024, 1, 31, 23, 59, 58, 0, time.Local)
+	line := captureHeaderPrefix + start.Format(time.RFC3339Nano)
+
+	got, ok := parseCaptureHeader(line)
+	if !ok || !got.Equal(start) {
+		t.Fatalf("parseCaptureHeader(%q) = %v, %v", line, got, ok)
+	}
+	if _, ok := parseCaptureHeader("23:59:59.000001  open  /tmp/a  Finder.123"); ok {
+		t.Error("data line parsed as capture header")
+	}
+
+	clock := newClockTracker(got)
+	for _, tc := range []struct {
+		clock string
+		want  time.Time
+	}{
+		{"23:59:59.5", time.Date(2024, 1, 31, 23, 59, 59, 5e8, time.Local)},
+		{"00:00:01", time.Date(2024, 2, 1, 0, 0, 1, 0, time.Local)},
+	} {
+		if got, ok := clock.resolve(tc.clock); !ok || !got.Equal(tc.want) {
+			t.Errorf("resolve(%q) = %v, want %v", tc.clock, got, tc.want)
+		}
+	}
+
+	// 第一条记录早于开始时刻时属于下一天
+	if got, _ := newClockTracker(got).resolve("00:00:01"); !got.Equal(time.Date(2024, 2, 1, 0, 0, 1, 0, time.Local)) {
+		t.Errorf("first record after midnight resolved to %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockRolloverThreshold 时刻回退超过该值时认为跨过了午夜
+const clockRolloverThreshold = time.Hour
+
+// clockTracker 将只包含时刻（如 12:34:56.789012）的时间戳还原为完整时间
+// 日志中的时刻明显回退时（跨过午夜），自动切换到下一天
+type clockTracker struct {
+	day  time.Time
+	last time.Duration
+}
+
+// newClockTracker 以base所在日期为起始日期创建时刻解析器
+// base同时作为第一个时刻的参照，早于base时刻的第一条记录属于下一天
+func newClockTracker(base time.Time) *clockTracker {
+	year, month, day := base.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, base.Location())
+	return &clockTracker{
+		day:  start,
+		last: base.Sub(start),
+	}
+}
+
+// resolve 解析时刻字符串并返回完整时间
+func (c *clockTracker) resolve(clock string) (time.Time, bool) {
+	offset, ok := parseClock(clock)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if offset < c.last-clockRolloverThreshold {
+		c.day = c.day.AddDate(0, 0, 1)
+	}
+	c.last = offset
+
+	return c.day.Add(offset), true
+}
+
+// parseClock 解析 HH:MM:SS 或 HH:MM:SS.ffffff 格式的时刻，返回距零点的时长
+func parseClock(clock string) (time.Duration, bool) {
+	parts := strings.Split(clock, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, false
+	}
+
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || seconds < 0 || seconds >= 61 {
+		return 0, false
+	}
+
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), true
+}
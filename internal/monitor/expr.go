@@ -0,0 +1,544 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// 过滤表达式是基于访问记录字段的布尔表达式，例如：
+//
+//	process =~ "node*" && op in (write, rename) && !path.startsWith("/tmp")
+//
+// 支持的语法：
+//   - 比较：field == value、!=、<、<=、>、>=，数值字段按数值比较，duration可以使用 10ms 等时长
+//   - 模式匹配：field =~ "pattern"，使用与通配符相同的语法，regex: 前缀表示正则表达式
+//   - 集合：field in (a, b, c)
+//   - 字符串方法：field.startsWith("x")、endsWith、contains、matches（正则表达式）
+//   - 逻辑运算：&&、||、!，以及括号
+//
+// 右侧的值可以是带引号的字符串、数字（可以为负数）或不带引号的单词
+// 双引号字符串按Go的规则转义，单引号字符串只处理 \' 和 \\，其他反斜杠原样保留，便于书写正则表达式
+// 不带引号的单词中包含 / 或 * 时可以包含 . 和 {a,b} 形式的花括号，如 path == /tmp/a.txt、path =~ *.{go,js}，
+// 其他包含 . 的值需要加引号
+
+// exprField 表达式中可以使用的字段
+type exprField struct {
+	kind   exprKind
+	path   bool // 是否为路径字段，=~ 按路径通配符匹配
+	str    func(*database.FileAccess) string
+	number func(*database.FileAccess) int64
+}
+
+// exprKind 字段的值类型
+type exprKind int
+
+const (
+	exprString exprKind = iota
+	exprNumber
+	exprDuration
+)
+
+// exprFields 字段名与访问记录字段的对应关系
+var exprFields = map[string]exprField{
+	"process":  {kind: exprString, str: func(a *database.FileAccess) string { return a.ProcessName }},
+	"path":     {kind: exprString, path: true, str: func(a *database.FileAccess) string { return a.FilePath }},
+	"target":   {kind: exprString, path: true, str: func(a *database.FileAccess) string { return a.TargetPath }},
+	"op":       {kind: exprString, str: func(a *database.FileAccess) string { return a.Operation }},
+	"category": {kind: exprString, str: func(a *database.FileAccess) string { return a.Category }},
+	"mode":     {kind: exprString, str: func(a *database.FileAccess) string { return a.Mode }},
+	"pid":      {kind: exprNumber, number: func(a *database.FileAccess) int64 { return int64(a.PID) }},
+	"ppid":     {kind: exprNumber, number: func(a *database.FileAccess) int64 { return int64(a.PPID) }},
+	"tid":      {kind: exprNumber, number: func(a *database.FileAccess) int64 { return int64(a.TID) }},
+	"fd":       {kind: exprNumber, number: func(a *database.FileAccess) int64 { return int64(a.FD) }},
+	"errno":    {kind: exprNumber, number: func(a *database.FileAccess) int64 { return int64(a.Errno) }},
+	"bytes":    {kind: exprNumber, number: func(a *database.FileAccess) int64 { return a.Bytes }},
+	"duration": {kind: exprDuration, number: func(a *database.FileAccess) int64 { return int64(a.Duration) }},
+}
+
+// exprAliases 字段的别名，与JSON中的字段名一致
+var exprAliases = map[string]string{
+	"process_name": "process",
+	"file_path":    "path",
+	"target_path":  "target",
+	"operation":    "op",
+}
+
+// exprNode 编译后的表达式节点
+type exprNode func(*database.FileAccess) bool
+
+// CompileFilter 编译过滤表达式，返回可用于查询和监控管道的过滤条件
+func CompileFilter(expr string) (database.AccessFilter, error) {
+	tokens, err := tokenizeExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("位置 %d: 多余的 '%s'", tok.pos, tok.text)
+	}
+
+	return func(access database.FileAccess) bool {
+		return node(&access)
+	}, nil
+}
+
+// tokenKind 词法单元的类型
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+// exprToken 词法单元
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// exprOperators 运算符，较长的运算符在前
+var exprOperators = []string{"==", "!=", "=~", "<=", ">=", "&&", "||", "!", "<", ">", "(", ")", ",", "."}
+
+// tokenizeExpr 将表达式拆分为词法单元
+func tokenizeExpr(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(expr) && expr[end] != byte(c) {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("位置 %d: 字符串没有结束的引号", i)
+			}
+
+			var text string
+			if c == '"' {
+				value, err := strconv.Unquote(expr[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("位置 %d: 无效的字符串 %s", i, expr[i:end+1])
+				}
+				text = value
+			} else {
+				text = singleQuoteReplacer.Replace(expr[i+1 : end])
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: text, pos: i})
+			i = end + 1
+
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			// 数字和时长，如 4096、0x1000、1.5ms、-1
+			end := i + 1
+			for end < len(expr) && (isExprWordChar(rune(expr[end])) || expr[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: expr[i:end], pos: i})
+			i = end
+
+		case isExprWordChar(c) || c == '/' || c == '*':
+			// 不带引号的单词，以及不带引号的路径或通配符
+			// 字段名中没有 / 和 *，包含它们的单词是值，其中的 . 不作为方法调用，如 /tmp/a.txt、*.log
+			// 值中的花括号连同其中的 , 属于同一个单词，如 *.{go,js}
+			end := i
+			for end < len(expr) {
+				ch := rune(expr[end])
+				if ch == '{' && strings.ContainsAny(expr[i:end], "/*") {
+					if close := matchingBrace(expr, end); close > 0 {
+						end = close + 1
+						continue
+					}
+				}
+				if !isExprWordChar(ch) && !strings.ContainsRune("/*-", ch) &&
+					(ch != '.' || !strings.ContainsAny(expr[i:end], "/*")) {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: expr[i:end], pos: i})
+			i = end
+
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("位置 %d: 无法识别的字符 '%c'", i, c)
+			}
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// singleQuoteReplacer 单引号字符串中的转义
+var singleQuoteReplacer = strings.NewReplacer(`\'`, `'`, `\\`, `\`)
+
+// matchingBrace 返回与 expr[open] 处的 { 配对的 } 的位置，花括号可以嵌套，遇到空白、引号或 ) 时返回-1
+func matchingBrace(expr string, open int) int {
+	depth := 0
+	for i := open; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		case c == ' ' || c == '\t' || c == '"' || c == '\'' || c == ')':
+			return -1
+		}
+	}
+	return -1
+}
+
+// isExprWordChar 判断是否为单词中的字符
+func isExprWordChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// exprParser 递归下降解析器
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// peek 返回当前的词法单元
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+// next 返回当前的词法单元并前进
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept 当前词法单元为指定运算符时前进并返回true
+func (p *exprParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect 要求当前词法单元为指定运算符
+func (p *exprParser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("位置 %d: 需要 '%s'，实际为 %s", tok.pos, op, describeToken(tok))
+	}
+	return nil
+}
+
+// parseOr 解析 a || b
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(a *database.FileAccess) bool { return l(a) || r(a) }
+	}
+	return left, nil
+}
+
+// parseAnd 解析 a && b
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(a *database.FileAccess) bool { return l(a) && r(a) }
+	}
+	return left, nil
+}
+
+// parseUnary 解析 !a 和括号
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(a *database.FileAccess) bool { return !node(a) }, nil
+	}
+
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	return p.parseCondition()
+}
+
+// parseCondition 解析以字段开头的条件
+func (p *exprParser) parseCondition() (exprNode, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return nil, fmt.Errorf("位置 %d: 需要字段名，实际为 %s", tok.pos, describeToken(tok))
+	}
+
+	name := tok.text
+	if alias, ok := exprAliases[name]; ok {
+		name = alias
+	}
+	field, ok := exprFields[name]
+	if !ok {
+		return nil, fmt.Errorf("位置 %d: 未知的字段 '%s'，可用字段: %s", tok.pos, tok.text, strings.Join(sortedExprFields(), ", "))
+	}
+
+	if p.accept(".") {
+		return p.parseMethod(field, name)
+	}
+
+	op := p.next()
+	if op.kind == tokenIdent && op.text == "in" {
+		return p.parseIn(field, name)
+	}
+	if op.kind != tokenOperator {
+		return nil, fmt.Errorf("位置 %d: 字段 %s 后需要比较运算符，实际为 %s", op.pos, name, describeToken(op))
+	}
+
+	value := p.next()
+	if value.kind != tokenString && value.kind != tokenNumber && value.kind != tokenIdent {
+		return nil, fmt.Errorf("位置 %d: 运算符 %s 后需要值，实际为 %s", value.pos, op.text, describeToken(value))
+	}
+
+	switch op.text {
+	case "=~":
+		if field.kind != exprString {
+			return nil, fmt.Errorf("位置 %d: 数值字段 %s 不支持 =~", op.pos, name)
+		}
+		matcher, err := compilePattern(value.text, field.path)
+		if err != nil {
+			return nil, fmt.Errorf("位置 %d: %w", value.pos, err)
+		}
+		return func(a *database.FileAccess) bool { return matcher.match(field.str(a)) }, nil
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		return compileComparison(field, name, op, value)
+	}
+
+	return nil, fmt.Errorf("位置 %d: 字段 %s 后不支持运算符 '%s'", op.pos, name, op.text)
+}
+
+// compileComparison 编译比较条件
+func compileComparison(field exprField, name string, op exprToken, value exprToken) (exprNode, error) {
+	if field.kind == exprString {
+		want := value.text
+		switch op.text {
+		case "==":
+			return func(a *database.FileAccess) bool { return field.str(a) == want }, nil
+		case "!=":
+			return func(a *database.FileAccess) bool { return field.str(a) != want }, nil
+		}
+		return nil, fmt.Errorf("位置 %d: 字符串字段 %s 只支持 ==、!=、=~ 和 in", op.pos, name)
+	}
+
+	want, err := parseExprNumber(field, value)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := map[string]func(int64) bool{
+		"==": func(v int64) bool { return v == want },
+		"!=": func(v int64) bool { return v != want },
+		"<":  func(v int64) bool { return v < want },
+		"<=": func(v int64) bool { return v <= want },
+		">":  func(v int64) bool { return v > want },
+		">=": func(v int64) bool { return v >= want },
+	}[op.text]
+	return func(a *database.FileAccess) bool { return compare(field.number(a)) }, nil
+}
+
+// parseExprNumber 解析数值字段比较的值，duration字段支持 10ms 等时长，纯数字按纳秒处理
+func parseExprNumber(field exprField, value exprToken) (int64, error) {
+	if field.kind == exprDuration {
+		if d, err := time.ParseDuration(value.text); err == nil {
+			return int64(d), nil
+		}
+	}
+
+	n, err := strconv.ParseInt(value.text, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("位置 %d: 无效的数值 '%s'", value.pos, value.text)
+	}
+	return n, nil
+}
+
+// parseIn 解析 field in (a, b, c)
+func (p *exprParser) parseIn(field exprField, name string) (exprNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	strs := make(map[string]bool)
+	numbers := make(map[int64]bool)
+	for {
+		value := p.next()
+		if value.kind != tokenString && value.kind != tokenNumber && value.kind != tokenIdent {
+			return nil, fmt.Errorf("位置 %d: in 列表中需要值，实际为 %s", value.pos, describeToken(value))
+		}
+
+		if field.kind == exprString {
+			strs[value.text] = true
+		} else {
+			n, err := parseExprNumber(field, value)
+			if err != nil {
+				return nil, err
+			}
+			numbers[n] = true
+		}
+
+		if p.accept(")") {
+			break
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+
+	if field.kind == exprString {
+		return func(a *database.FileAccess) bool { return strs[field.str(a)] }, nil
+	}
+	return func(a *database.FileAccess) bool { return numbers[field.number(a)] }, nil
+}
+
+// parseMethod 解析 field.method("arg")
+func (p *exprParser) parseMethod(field exprField, name string) (exprNode, error) {
+	method := p.next()
+	if method.kind != tokenIdent {
+		return nil, fmt.Errorf("位置 %d: 需要方法名，实际为 %s", method.pos, describeToken(method))
+	}
+	if field.kind != exprString {
+		return nil, fmt.Errorf("位置 %d: 数值字段 %s 不支持方法调用", method.pos, name)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	arg := p.next()
+	if arg.kind != tokenString {
+		return nil, fmt.Errorf("位置 %d: 方法 %s 的参数需要带引号的字符串", arg.pos, method.text)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	value := arg.text
+	switch method.text {
+	case "startsWith":
+		return func(a *database.FileAccess) bool { return strings.HasPrefix(field.str(a), value) }, nil
+	case "endsWith":
+		return func(a *database.FileAccess) bool { return strings.HasSuffix(field.str(a), value) }, nil
+	case "contains":
+		return func(a *database.FileAccess) bool { return strings.Contains(field.str(a), value) }, nil
+	case "matches":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("位置 %d: 正则表达式 '%s' 无效: %w", arg.pos, value, err)
+		}
+		return func(a *database.FileAccess) bool { return re.MatchString(field.str(a)) }, nil
+	}
+
+	return nil, fmt.Errorf("位置 %d: 未知的方法 '%s'，可用方法: startsWith, endsWith, contains, matches", method.pos, method.text)
+}
+
+// describeToken 返回错误信息中词法单元的描述
+func describeToken(tok exprToken) string {
+	if tok.kind == tokenEOF {
+		return "表达式结尾"
+	}
+	return "'" + tok.text + "'"
+}
+
+// sortedExprFields 返回排序后的字段名
+func sortedExprFields() []string {
+	fields := make(map[string]bool, len(exprFields))
+	for name := range exprFields {
+		fields[name] = true
+	}
+	return sortedKeys(fields)
+}
+
+// SetFilterExpression 设置监控时的过滤表达式，只记录满足表达式的访问
+// 表达式无效时返回错误且不修改当前设置
+func SetFilterExpression(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		ResetFilterExpression()
+		return nil
+	}
+
+	filter, err := CompileFilter(expr)
+	if err != nil {
+		return err
+	}
+
+	updateFilters(func(f *filterSet) { f.capture, f.expression = filter, expr })
+	log.Printf("已设置过滤表达式: %s", expr)
+	return nil
+}
+
+// GetFilterExpression 获取当前的过滤表达式
+func GetFilterExpression() string {
+	return currentFilters().expression
+}
+
+// ResetFilterExpression 重置过滤表达式
+func ResetFilterExpression() {
+	updateFilters(func(f *filterSet) { f.capture, f.expression = nil, "" })
+	log.Println("已重置过滤表达式")
+}
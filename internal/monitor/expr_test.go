@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+func TestCompileFilter(t *testing.T) {
+	node := database.FileAccess{
+		ProcessName: "node",
+		PID:         1234,
+		FilePath:    "/src/app/build/a.js",
+		Operation:   "write",
+		Category:    database.CategoryWrite,
+		Bytes:       4096,
+		Errno:       -1,
+		Duration:    15 * time.Millisecond,
+	}
+	vim := database.FileAccess{
+		ProcessName: "vim",
+		PID:         88,
+		FilePath:    "/tmp/notes.txt",
+		TargetPath:  "/tmp/notes.txt~",
+		Operation:   "rename",
+	}
+
+	tests := []struct {
+		expr   string
+		access database.FileAccess
+		want   bool
+	}{
+		// README中的示例
+		{`process =~ "node*" && op in (write, rename) && !path.startsWith("/tmp")`, node, true},
+		{`process =~ "node*" && op in (write, rename) && !path.startsWith("/tmp")`, vim, false},
+
+		// && 优先于 ||，! 只作用于紧跟的条件
+		{`process == vim || process == node && op == rename`, node, false},
+		{`process == vim || process == node && op == rename`, vim, true},
+		{`(process == vim || process == node) && op == write`, node, true},
+		{`!process == vim && op == write`, node, true},
+		{`!(process == node && op == write)`, node, false},
+
+		// in 列表
+		{`pid in (1, 88, 1234)`, node, true},
+		{`op in ("open", read)`, node, false},
+		{`path in (/tmp/notes.txt, /tmp/other.txt)`, vim, true},
+
+		// 字符串方法
+		{`path.endsWith(".js")`, node, true},
+		{`path.contains("/build/")`, node, true},
+		{`target.matches("~$")`, vim, true},
+		{`file_path.startsWith("/src")`, node, true},
+
+		// 数值、时长和负数
+		{`bytes >= 0x1000 && bytes < 8192`, node, true},
+		{`duration > 10ms`, node, true},
+		{`duration <= 10ms`, node, false},
+		{`errno == -1`, node, true},
+		{`errno != -1`, vim, true},
+
+		// 不带引号的路径和通配符
+		{`path == /tmp/notes.txt`, vim, true},
+		{`path =~ *.js`, node, true},
+		{`path =~ /src/**/*.js`, node, true},
+		{`target =~ "*.txt~"`, vim, true},
+		{`path =~ *.{go,js}`, node, true},
+		{`path =~ /src/**/*.{go,ts}`, node, false},
+		{`path =~ /{src,srv}/**/*.{js,{ts,tsx}} && op in (write, rename)`, node, true},
+		{`op in (write, read) && path =~ *.{js}`, node, true},
+
+		// 单引号字符串
+		{`process == 'node' && path.endsWith('.js')`, node, true},
+		{`process == 'it\'s'`, database.FileAccess{ProcessName: "it's"}, true},
+		{`path == 'C:\\tmp'`, database.FileAccess{FilePath: `C:\tmp`}, true},
+		{`path.matches('\.js$')`, node, true},
+	}
+
+	for _, tc := range tests {
+		filter, err := CompileFilter(tc.expr)
+		if err != nil {
+			t.Errorf("CompileFilter(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := filter(tc.access); got != tc.want {
+			t.Errorf("%q on %s: got %v, want %v", tc.expr, tc.access.FilePath, got, tc.want)
+		}
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`process ==`, "位置 10"},
+		{`name == vim`, "位置 0: 未知的字段"},
+		{`process == vim &&`, "位置 17"},
+		{`(process == vim`, "位置 15: 需要 ')'"},
+		{`process == vim)`, "位置 14: 多余的"},
+		{`pid =~ 12`, "位置 4"},
+		{`pid == abc`, "位置 7: 无效的数值"},
+		{`process < vim`, "位置 8"},
+		{`path.startsWith(/tmp)`, "位置 16"},
+		{`path.lower("x")`, "位置 5: 未知的方法"},
+		{`path.matches("(")`, "位置 13"},
+		{`op in (write, )`, "位置 14"},
+		{`process == "vim`, "位置 11: 字符串没有结束的引号"},
+		{`process == vim # x`, "位置 15: 无法识别的字符"},
+		{`path =~ *.{go,js`, "位置 10: 无法识别的字符"},
+		{`process == {a,b}`, "位置 11: 无法识别的字符"},
+	}
+
+	for _, tc := range tests {
+		_, err := CompileFilter(tc.expr)
+		if err == nil {
+			t.Errorf("CompileFilter(%q) expected error", tc.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("CompileFilter(%q) = %v, want %q", tc.expr, err, tc.want)
+		}
+	}
+}
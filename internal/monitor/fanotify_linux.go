@@ -0,0 +1,194 @@
+//go:build linux
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"github.com/mine/fileWatch/internal/database"
+	"golang.org/x/sys/unix"
+)
+
+// fanotify事件元数据的大小
+const fanotifyMetadataSize = int(unsafe.Sizeof(unix.FanotifyEventMetadata{}))
+
+// fanotify监听的事件类型
+const fanotifyMask = unix.FAN_OPEN | unix.FAN_ACCESS | unix.FAN_MODIFY |
+	unix.FAN_CLOSE_WRITE | unix.FAN_CLOSE_NOWRITE
+
+// fanotifyOperations fanotify事件位与操作类型的对应关系
+var fanotifyOperations = []struct {
+	mask      uint64
+	operation string
+}{
+	{unix.FAN_OPEN, "open"},
+	{unix.FAN_ACCESS, "read"},
+	{unix.FAN_MODIFY, "write"},
+	{unix.FAN_CLOSE_WRITE, "close"},
+	{unix.FAN_CLOSE_NOWRITE, "close"},
+	{unix.FAN_OPEN_EXEC, "open_exec"},
+}
+
+// FanotifySource 基于Linux fanotify的事件源，需要root权限
+type FanotifySource struct {
+	roots  []string
+	file   *os.File
+	events chan database.FileAccess
+	errors chan error
+}
+
+// NewFanotifySource 创建fanotify事件源，监控roots所在的挂载点
+func NewFanotifySource(roots []string) *FanotifySource {
+	return &FanotifySource{
+		roots:  roots,
+		events: make(chan database.FileAccess, batchSize),
+		errors: make(chan error, 1),
+	}
+}
+
+// Roots 返回监控的目录
+func (s *FanotifySource) Roots() []string {
+	return s.roots
+}
+
+// newFanotifySource 根据包含目录通配符推导出的目录创建fanotify事件源
+func newFanotifySource(config sourceConfig) (EventSource, error) {
+	return NewFanotifySource(config.roots), nil
+}
+
+// Name 返回事件源名称
+func (s *FanotifySource) Name() string {
+	return SourceFanotify
+}
+
+// Start 初始化fanotify并开始读取事件
+func (s *FanotifySource) Start() error {
+	fd, err := unix.FanotifyInit(unix.FAN_CLASS_NOTIF|unix.FAN_CLOEXEC|unix.FAN_NONBLOCK,
+		unix.O_RDONLY|unix.O_LARGEFILE|unix.O_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("初始化fanotify失败（需要root权限）: %w", err)
+	}
+
+	for _, root := range s.roots {
+		// FAN_OPEN_EXEC需要Linux 5.0以上的内核，不支持时不记录exec事件
+		err := unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_MOUNT, fanotifyMask|unix.FAN_OPEN_EXEC, unix.AT_FDCWD, root)
+		if errors.Is(err, unix.EINVAL) {
+			err = unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_MOUNT, fanotifyMask, unix.AT_FDCWD, root)
+		}
+		if err != nil {
+			unix.Close(fd)
+			return fmt.Errorf("监控挂载点 %s 失败: %w", root, err)
+		}
+	}
+
+	// 非阻塞描述符交给运行时轮询，关闭文件即可让读取返回
+	s.file = os.NewFile(uintptr(fd), "fanotify")
+
+	go s.readEvents()
+	return nil
+}
+
+// readEvents 循环读取并解析fanotify事件
+func (s *FanotifySource) readEvents() {
+	defer close(s.events)
+	defer close(s.errors)
+
+	selfPID := int32(os.Getpid())
+	buf := make([]byte, 4096*fanotifyMetadataSize)
+
+	for {
+		n, err := s.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				s.errors <- fmt.Errorf("读取fanotify事件失败: %w", err)
+			}
+			return
+		}
+
+		for offset := 0; offset+fanotifyMetadataSize <= n; {
+			meta := (*unix.FanotifyEventMetadata)(unsafe.Pointer(&buf[offset]))
+			if int(meta.Event_len) < fanotifyMetadataSize || meta.Vers != unix.FANOTIFY_METADATA_VERSION {
+				break
+			}
+			offset += int(meta.Event_len)
+
+			if meta.Fd < 0 {
+				continue
+			}
+
+			// 忽略本进程产生的事件，读取/proc本身也会触发fanotify
+			if meta.Pid == selfPID {
+				unix.Close(int(meta.Fd))
+				continue
+			}
+
+			path, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(meta.Fd)))
+			unix.Close(int(meta.Fd))
+			if err != nil {
+				continue
+			}
+
+			for _, access := range parseFanotifyEvent(meta.Mask, int(meta.Pid), path) {
+				s.events <- access
+			}
+		}
+	}
+}
+
+// parseFanotifyEvent 将一条fanotify事件转换为访问记录，一条事件可能包含多个操作
+func parseFanotifyEvent(mask uint64, pid int, path string) []database.FileAccess {
+	processName := lookupProcessName(pid)
+	if processName == "" {
+		// 进程已退出，只能记录pid
+		processName = strconv.Itoa(pid)
+	}
+	var accesses []database.FileAccess
+	seen := make(map[string]bool)
+	now := time.Now()
+
+	for _, op := range fanotifyOperations {
+		if mask&op.mask == 0 || seen[op.operation] {
+			continue
+		}
+		access := database.FileAccess{
+			Timestamp:   now,
+			ProcessName: processName,
+			PID:         pid,
+			FilePath:    path,
+			Operation:   op.operation,
+			IsDir:       mask&unix.FAN_ONDIR != 0,
+		}
+
+		// exec事件用于维护进程表，进程和路径条件在管道中检查
+		if op.mask != unix.FAN_OPEN_EXEC && !isTrackedAccess(&access, SourceFanotify) {
+			continue
+		}
+		seen[op.operation] = true
+		accesses = append(accesses, access)
+	}
+
+	return accesses
+}
+
+// Stop 关闭fanotify描述符
+func (s *FanotifySource) Stop() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// Events 返回访问记录通道
+func (s *FanotifySource) Events() <-chan database.FileAccess {
+	return s.events
+}
+
+// Errors 返回错误通道
+func (s *FanotifySource) Errors() <-chan error {
+	return s.errors
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// newFanotifySource fanotify仅在Linux上可用
+func newFanotifySource(sourceConfig) (EventSource, error) {
+	return nil, errors.New("fanotify事件源仅支持Linux")
+}
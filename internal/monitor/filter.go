@@ -0,0 +1,227 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// 通配符的语法前缀，未指定时按glob语法解析
+const (
+	patternPrefixGlob  = "glob:"
+	patternPrefixRegex = "regex:"
+)
+
+// patternMatcher 编译后的路径或进程名匹配模式
+type patternMatcher interface {
+	match(value string) bool
+	String() string
+}
+
+// regexMatcher 正则表达式匹配模式，匹配字符串中的任意位置，需要完整匹配时使用 ^ 和 $
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+// match 判断字符串是否匹配正则表达式
+func (m *regexMatcher) match(value string) bool {
+	return m.re.MatchString(value)
+}
+
+// String 返回带 regex: 前缀的正则表达式
+func (m *regexMatcher) String() string {
+	return patternPrefixRegex + m.re.String()
+}
+
+// filterSet 当前会话编译后的路径和进程过滤条件
+// 通配符在设置时编译一次，匹配每条记录时不再重复解析
+// 过滤条件发布后不再修改，修改时复制一份整体替换，解析事件的goroutine无需加锁即可读取
+type filterSet struct {
+	includePatterns []string // 原始的包含目录通配符，用于显示和推导监控的根目录
+	excludePatterns []string
+	processPatterns []string
+
+	include []patternMatcher
+	exclude []patternMatcher
+	process []patternMatcher
+	capture database.AccessFilter // 过滤表达式，未设置时为空
+	ignore  []*ignoreRule         // 忽略规则，匹配任意一条时不记录
+	rules   []filterRule          // 规则列表，第一条匹配的规则生效
+	scopes  []processScope        // 进程的路径范围，使用第一个进程名匹配的范围
+
+	expression  string            // 原始的过滤表达式，与capture一起发布
+	ignoreFiles []string          // 会话引用的忽略文件或目录，与gitignore一起发布
+	gitignore   *gitignoreMatcher // 会话引用的gitignore文件，未设置时为空
+}
+
+var (
+	// 全局变量，用于存储当前会话编译后的过滤条件
+	activeFilters atomic.Pointer[filterSet]
+
+	// filtersMu 串行化对过滤条件的修改，读取时不需要加锁
+	filtersMu sync.Mutex
+)
+
+// currentFilters 获取当前的过滤条件，返回的过滤条件不会被修改
+func currentFilters() *filterSet {
+	if filters := activeFilters.Load(); filters != nil {
+		return filters
+	}
+	return &filterSet{}
+}
+
+// updateFilters 复制当前的过滤条件，修改后整体替换
+func updateFilters(update func(*filterSet)) {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+
+	filters := *currentFilters()
+	update(&filters)
+	activeFilters.Store(&filters)
+}
+
+// FilterPatterns 运行中替换的包含目录、排除目录和进程通配符，为nil的列表保持不变，空列表表示清空
+type FilterPatterns struct {
+	IncludePatterns []string `json:"includePatterns"`
+	ExcludePatterns []string `json:"excludePatterns"`
+	ProcessPatterns []string `json:"processPatterns"`
+}
+
+// ReplacePatterns 同时替换包含目录、排除目录和进程通配符，不需要重新启动监控
+// 所有模式编译成功后一次性发布，任意一个无效时返回错误且不修改当前设置
+// 包含目录超出运行中的事件源监控的目录时返回 ErrRootsChanged
+func ReplacePatterns(patterns FilterPatterns) error {
+	type replacement struct {
+		patterns []string
+		matchers []patternMatcher
+	}
+
+	compile := func(patterns []string, pathMode bool, name string) (*replacement, error) {
+		if patterns == nil {
+			return nil, nil
+		}
+		patterns = cleanPatterns(patterns)
+		matchers, err := compilePatterns(patterns, pathMode)
+		if err != nil {
+			return nil, fmt.Errorf("%s无效: %w", name, err)
+		}
+		return &replacement{patterns: patterns, matchers: matchers}, nil
+	}
+
+	include, err := compile(patterns.IncludePatterns, true, "包含目录通配符")
+	if err != nil {
+		return err
+	}
+	if include != nil {
+		// fanotify和inotify监控的目录在启动时确定，超出这些目录的路径不会产生事件
+		if err := checkSourceRoots(include.patterns); err != nil {
+			return err
+		}
+	}
+	exclude, err := compile(patterns.ExcludePatterns, true, "排除目录通配符")
+	if err != nil {
+		return err
+	}
+	process, err := compile(patterns.ProcessPatterns, false, "进程通配符")
+	if err != nil {
+		return err
+	}
+
+	updateFilters(func(f *filterSet) {
+		if include != nil {
+			f.include, f.includePatterns = include.matchers, include.patterns
+		}
+		if exclude != nil {
+			f.exclude, f.excludePatterns = exclude.matchers, exclude.patterns
+		}
+		if process != nil {
+			f.process, f.processPatterns = process.matchers, process.patterns
+		}
+	})
+
+	log.Printf("已更新过滤条件，包含路径通配符: %s, 排除路径通配符: %s, 进程通配符: %s",
+		GetIncludePattern(), GetExcludePattern(), GetProcessPattern())
+	return nil
+}
+
+// compilePattern 编译单个模式，regex: 前缀表示正则表达式，glob: 前缀或无前缀表示通配符
+// pathMode 为true时按路径匹配，否则按进程名等名称匹配
+func compilePattern(pattern string, pathMode bool) (patternMatcher, error) {
+	if expr, ok := strings.CutPrefix(pattern, patternPrefixRegex); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式 '%s' 无效: %w", expr, err)
+		}
+		return &regexMatcher{re: re}, nil
+	}
+
+	pattern = strings.TrimPrefix(pattern, patternPrefixGlob)
+	if pathMode {
+		return compilePathGlob(pattern)
+	}
+	return compileNameGlob(pattern)
+}
+
+// compilePatterns 编译一组模式，任意一个无效时返回错误
+func compilePatterns(patterns []string, pathMode bool) ([]patternMatcher, error) {
+	matchers := make([]patternMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		matcher, err := compilePattern(pattern, pathMode)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// matchPath 判断路径是否满足包含和排除条件
+func (f *filterSet) matchPath(path string) bool {
+	// 如果设置了包含模式，则只记录匹配任意一个模式的路径
+	if len(f.include) > 0 && !matchAnyPattern(path, f.include) {
+		return false
+	}
+
+	// 如果设置了排除模式，则排除匹配任意一个模式的路径
+	return !matchAnyPattern(path, f.exclude)
+}
+
+// matchProcess 判断进程名是否满足进程条件，未设置时记录所有进程
+func (f *filterSet) matchProcess(processName string) bool {
+	return len(f.process) == 0 || matchAnyPattern(processName, f.process)
+}
+
+// matchIgnore 返回路径匹配的第一条忽略规则，没有匹配时返回nil
+func (f *filterSet) matchIgnore(path string) *ignoreRule {
+	for _, rule := range f.ignore {
+		if rule.match(path) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// matchGitignore 判断路径是否被会话引用的gitignore文件忽略
+func (f *filterSet) matchGitignore(path string, isDir bool) bool {
+	return f.gitignore != nil && f.gitignore.match(path, isDir)
+}
+
+// matchCapture 判断访问记录是否满足过滤表达式，未设置时记录所有访问
+func (f *filterSet) matchCapture(access database.FileAccess) bool {
+	return f.capture == nil || f.capture(access)
+}
+
+// matchAnyPattern 判断字符串是否匹配任意一个模式
+func matchAnyPattern(value string, matchers []patternMatcher) bool {
+	for _, matcher := range matchers {
+		if matcher.match(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReplacePatternsSourceRoots(t *testing.T) {
+	if err := SetIncludePattern("/src/app/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetIncludePattern()
+	sourceRoots = []string{"/src/app"}
+	defer func() { sourceRoots = nil }()
+
+	tests := []struct {
+		include []string
+		err     error
+	}{
+		{[]string{"/src/app/internal/**", "/src/app/cmd/*.go"}, nil},
+		{[]string{"/src/**"}, ErrRootsChanged},
+		{[]string{"/src/app/**", "/etc/**"}, ErrRootsChanged},
+		{[]string{}, ErrRootsChanged},
+		{nil, nil},
+	}
+
+	for _, tc := range tests {
+		before := GetIncludePatterns()
+		err := ReplacePatterns(FilterPatterns{IncludePatterns: tc.include})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%q: err = %v, want %v", tc.include, err, tc.err)
+		}
+		if err != nil && !reflect.DeepEqual(GetIncludePatterns(), before) {
+			t.Errorf("%q: include patterns changed to %q after error", tc.include, GetIncludePatterns())
+		}
+	}
+
+	// 状态中显示事件源实际监控的目录，而不是根据新的通配符推导的目录
+	defer func(name string) { sourceName = name }(sourceName)
+	sourceName = SourceInotify
+	if err := ReplacePatterns(FilterPatterns{IncludePatterns: []string{"/src/app/internal/**"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := GetMonitorCommand(), "inotify /src/app (无进程信息)"; got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+}
+
+func TestReplacePatterns(t *testing.T) {
+	defer ResetIncludePattern()
+	defer ResetExcludePattern()
+	defer ResetProcessPattern()
+
+	tests := []struct {
+		name     string
+		patterns FilterPatterns
+		include  []string
+		exclude  []string
+		process  []string
+		wantErr  bool
+	}{
+		{
+			name:     "non-empty lists replace",
+			patterns: FilterPatterns{IncludePatterns: []string{"/src/**"}, ExcludePatterns: []string{"**/*.o"}, ProcessPatterns: []string{"make"}},
+			include:  []string{"/src/**"},
+			exclude:  []string{"**/*.o"},
+			process:  []string{"make"},
+		},
+		{
+			name:     "omitted lists are kept",
+			patterns: FilterPatterns{ExcludePatterns: []string{"**/*.tmp", " "}},
+			include:  []string{"/src/**"},
+			exclude:  []string{"**/*.tmp"},
+			process:  []string{"make"},
+		},
+		{
+			name:     "empty lists clear",
+			patterns: FilterPatterns{IncludePatterns: []string{}, ProcessPatterns: []string{}},
+			include:  []string{},
+			exclude:  []string{"**/*.tmp"},
+			process:  []string{},
+		},
+		{
+			name:     "all lists replaced together",
+			patterns: FilterPatterns{IncludePatterns: []string{"regex:^/srv/"}, ExcludePatterns: []string{}, ProcessPatterns: []string{"node*", "deno"}},
+			include:  []string{"regex:^/srv/"},
+			exclude:  []string{},
+			process:  []string{"node*", "deno"},
+		},
+		{
+			name:     "an invalid pattern changes nothing",
+			patterns: FilterPatterns{IncludePatterns: []string{"/data/**"}, ProcessPatterns: []string{"regex:("}},
+			include:  []string{"regex:^/srv/"},
+			exclude:  []string{},
+			process:  []string{"node*", "deno"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		err := ReplacePatterns(tc.patterns)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+		filters := currentFilters()
+		if !reflect.DeepEqual(GetIncludePatterns(), tc.include) || len(filters.include) != len(tc.include) {
+			t.Errorf("%s: include = %q, want %q", tc.name, GetIncludePatterns(), tc.include)
+		}
+		if !reflect.DeepEqual(GetExcludePatterns(), tc.exclude) || len(filters.exclude) != len(tc.exclude) {
+			t.Errorf("%s: exclude = %q, want %q", tc.name, GetExcludePatterns(), tc.exclude)
+		}
+		if !reflect.DeepEqual(GetProcessPatterns(), tc.process) || len(filters.process) != len(tc.process) {
+			t.Errorf("%s: process = %q, want %q", tc.name, GetProcessPatterns(), tc.process)
+		}
+	}
+}
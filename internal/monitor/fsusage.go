@@ -0,0 +1,582 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// fs_usage的过滤模式，对应 -f 参数
+const (
+	FSUsageModeFilesystem = "filesystem"
+	FSUsageModePathname   = "pathname"
+	FSUsageModeExec       = "exec"
+	FSUsageModeDiskIO     = "diskio"
+	FSUsageModeCacheHit   = "cachehit"
+)
+
+// FSUsageModes 支持的fs_usage过滤模式
+var FSUsageModes = []string{
+	FSUsageModeFilesystem,
+	FSUsageModePathname,
+	FSUsageModeExec,
+	FSUsageModeDiskIO,
+	FSUsageModeCacheHit,
+}
+
+// FSUsageOptions fs_usage事件源的配置
+type FSUsageOptions struct {
+	Modes   []string `json:"modes"`   // -f过滤模式，为空时只使用filesystem
+	Targets []string `json:"targets"` // 只监控指定的进程PID或命令名，为空时监控所有进程
+}
+
+// 全局变量，用于存储fs_usage事件源的配置
+var fsUsageOptions FSUsageOptions
+
+// fsUsageDiskIORegex 匹配diskio模式的操作，如 RdData[A]、WrMeta[AN]、PgIn
+var fsUsageDiskIORegex = regexp.MustCompile(`^(Rd|Wr|PgIn|PgOut|Tr)[A-Za-z]*(\[[A-Za-z]*\])?$`)
+
+// FSUsageSource 基于macOS fs_usage命令的事件源
+type FSUsageSource struct {
+	args    []string // fs_usage命令的参数（不含sudo）
+	capture bool     // 是否保存原始输出
+	cmd     *exec.Cmd
+	events  chan database.FileAccess
+	errors  chan error
+}
+
+// NewFSUsageSource 按当前的fs_usage配置创建事件源
+func NewFSUsageSource() *FSUsageSource {
+	return newFSUsageSource(GetFSUsageArgs(), captureEnabled)
+}
+
+// newFSUsageSource 使用指定的参数创建fs_usage事件源
+func newFSUsageSource(args []string, capture bool) *FSUsageSource {
+	return &FSUsageSource{
+		args:    args,
+		capture: capture,
+		events:  make(chan database.FileAccess, batchSize),
+		errors:  make(chan error, 1),
+	}
+}
+
+// Name 返回事件源名称
+func (s *FSUsageSource) Name() string {
+	return "fs_usage"
+}
+
+// Start 启动fs_usage命令并开始解析输出
+func (s *FSUsageSource) Start() error {
+	// 执行fs_usage命令，增加-w参数以显示完整路径
+	s.cmd = exec.Command("sudo", s.args...)
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建管道失败: %w", err)
+	}
+
+	// 需要保存原始输出时，将命令输出同时写入捕获文件
+	var reader io.Reader = stdout
+	var capture *rotatingWriter
+	if s.capture {
+		if capture, err = newRotatingWriter(); err != nil {
+			return err
+		}
+		reader = io.TeeReader(stdout, capture)
+	}
+
+	if err := s.cmd.Start(); err != nil {
+		if capture != nil {
+			capture.Close()
+		}
+		return fmt.Errorf("启动fs_usage命令失败: %w", err)
+	}
+
+	// 使用扫描器读取命令输出
+	go func() {
+		defer close(s.events)
+		defer close(s.errors)
+		if capture != nil {
+			defer capture.Close()
+		}
+
+		parser := newFSUsageParser(time.Now())
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			// 解析fs_usage输出行
+			if access := parser.parseLine(scanner.Text()); access != nil {
+				s.events <- *access
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			s.errors <- fmt.Errorf("读取fs_usage输出失败: %w", err)
+		}
+
+		// 输出读取完毕后回收子进程
+		s.cmd.Wait()
+	}()
+
+	return nil
+}
+
+// Stop 停止fs_usage命令
+func (s *FSUsageSource) Stop() error {
+	if s.cmd == nil || s.cmd.Process == nil {
+		return nil
+	}
+
+	if err := s.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("停止fs_usage命令失败: %w", err)
+	}
+	return nil
+}
+
+// Events 返回访问记录通道
+func (s *FSUsageSource) Events() <-chan database.FileAccess {
+	return s.events
+}
+
+// Errors 返回错误通道
+func (s *FSUsageSource) Errors() <-chan error {
+	return s.errors
+}
+
+// maxTrackedFDs 解析器最多记录的文件描述符数量，超出后清空以避免无限增长
+const maxTrackedFDs = 100000
+
+// fdKey 标识某个进程的文件描述符
+type fdKey struct {
+	pid int
+	fd  int
+}
+
+// fsUsageParser 解析fs_usage命令的输出，保存跨行的解析状态
+// 记录open返回的文件描述符，用于还原只输出F=<fd>的read/write/close对应的文件
+type fsUsageParser struct {
+	clock *clockTracker
+	fds   map[fdKey]string
+}
+
+// newFSUsageParser 创建fs_usage输出解析器
+// fs_usage只输出时刻，base为输出中第一行所在的日期
+func newFSUsageParser(base time.Time) *fsUsageParser {
+	return &fsUsageParser{
+		clock: newClockTracker(base),
+		fds:   make(map[fdKey]string),
+	}
+}
+
+// parseLine 解析fs_usage命令的单行输出
+func (p *fsUsageParser) parseLine(line string) *database.FileAccess {
+	// 将行分割成字段
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return nil // 至少需要时间戳、操作类型和进程信息
+	}
+
+	// 提取时间戳，跳过标题行等非数据行
+	// 所有数据行都参与解析，以便正确处理跨越午夜的情况
+	timestamp, ok := p.clock.resolve(fields[0])
+	if !ok {
+		return nil
+	}
+
+	// 提取操作类型
+	operation := fields[1]
+
+	// 提取进程信息（通常是最后一个字段）
+	processInfo := fields[len(fields)-1]
+	processName, pid := parseProcessInfo(processInfo)
+
+	// 创建文件访问记录，并解析文件描述符等附加信息
+	access := &database.FileAccess{
+		Timestamp:   timestamp,
+		ProcessName: processName,
+		PID:         pid,
+		Operation:   operation,
+	}
+	parseFsUsageDetails(line, fields, access)
+
+	// 不同过滤模式的输出格式不同，diskio和cachehit模式的第一个路径是设备
+	access.Mode = fsUsageLineMode(operation)
+	filePath := ""
+	if access.Mode == FSUsageModeDiskIO || access.Mode == FSUsageModeCacheHit {
+		filePath = extractDiskIOPath(fields)
+	} else {
+		filePath = extractFilePathSimple(line, fields)
+	}
+
+	// 提取文件路径，没有路径时根据文件描述符还原
+	access.FilePath = p.trackFD(access, filePath)
+	if access.FilePath == "" {
+		return nil
+	}
+
+	// exec等进程事件用于维护进程表，在管道中再按会话条件过滤
+	access.Category = fsUsageCategory(operation, fields)
+	if access.Category == database.CategoryExec {
+		return access
+	}
+
+	// 只记录当前会话关注的操作
+	if !isTrackedAccess(access, SourceFSUsage) {
+		return nil
+	}
+
+	// 重命名、链接等操作会输出源路径和目标路径，进程和路径条件在管道中检查
+	if isTwoPathOperation(operation) {
+		access.TargetPath = extractTargetPath(fields)
+	}
+
+	return access
+}
+
+// fsUsageLineMode 根据操作类型判断输出行由哪个过滤模式产生
+// filesystem包含了pathname的全部调用，两者同时开启时无法区分，按filesystem记录
+func fsUsageLineMode(operation string) string {
+	switch {
+	case isDiskIOOperation(operation):
+		return FSUsageModeDiskIO
+	case strings.HasPrefix(operation, "CACHE_HIT"):
+		return FSUsageModeCacheHit
+	case OperationCategory(operation) == database.CategoryExec:
+		return FSUsageModeExec
+	case fsUsageModeEnabled(FSUsageModePathname) && !fsUsageModeEnabled(FSUsageModeFilesystem):
+		return FSUsageModePathname
+	}
+	return FSUsageModeFilesystem
+}
+
+// isDiskIOOperation 判断是否为diskio模式输出的磁盘读写操作
+func isDiskIOOperation(operation string) bool {
+	return fsUsageDiskIORegex.MatchString(operation)
+}
+
+// extractDiskIOPath 提取diskio输出行中的文件路径
+// 典型的输出行：12:00:00.000100  RdData[A]  D=0x0012a000  B=0x1000  /dev/disk1s1  /Users/me/file  0.000200 W  mds.123
+// 没有文件路径时（如文件系统元数据）返回设备路径
+func extractDiskIOPath(fields []string) string {
+	device := ""
+	for _, field := range fields[2:] {
+		if !strings.HasPrefix(field, "/") {
+			continue
+		}
+		if !strings.HasPrefix(field, "/dev/") {
+			return field
+		}
+		if device == "" {
+			device = field
+		}
+	}
+	return device
+}
+
+// fsUsageCategory 返回操作分类，open根据输出中的打开模式（如 (_W____)）区分读写
+func fsUsageCategory(operation string, fields []string) string {
+	category := OperationCategory(operation)
+	if !isOpenOperation(operation) {
+		return category
+	}
+
+	for _, field := range fields[2:] {
+		if strings.HasPrefix(field, "(") && strings.HasSuffix(field, ")") {
+			if strings.Contains(field, "C") {
+				return database.CategoryCreate
+			}
+			if strings.Contains(field, "W") {
+				return database.CategoryWrite
+			}
+			break
+		}
+	}
+	return category
+}
+
+// isTwoPathOperation 判断是否为同时包含源路径和目标路径的操作
+func isTwoPathOperation(operation string) bool {
+	switch operation {
+	case "rename", "renameat", "renamex_np", "renameatx_np", "link", "linkat",
+		"symlink", "symlinkat", "clonefile", "clonefileat", "fclonefileat", "exchangedata":
+		return true
+	}
+	return false
+}
+
+// extractTargetPath 提取输出行中的第二个路径作为目标路径
+func extractTargetPath(fields []string) string {
+	found := false
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "/") {
+			continue
+		}
+		if found {
+			return field
+		}
+		found = true
+	}
+	return ""
+}
+
+// trackFD 维护文件描述符与路径的对应关系，返回本次操作对应的文件路径
+func (p *fsUsageParser) trackFD(access *database.FileAccess, filePath string) string {
+	if access.FD == 0 {
+		return filePath
+	}
+
+	key := fdKey{pid: access.PID, fd: access.FD}
+
+	// open成功时记录返回的描述符
+	if filePath != "" {
+		if isOpenOperation(access.Operation) && access.Errno == 0 {
+			if len(p.fds) >= maxTrackedFDs {
+				p.fds = make(map[fdKey]string)
+			}
+			p.fds[key] = filePath
+		}
+		return filePath
+	}
+
+	filePath = p.fds[key]
+
+	// 关闭后描述符可能被复用
+	if isCloseOperation(access.Operation) {
+		delete(p.fds, key)
+	}
+	return filePath
+}
+
+// isOpenOperation 判断是否为打开文件并返回描述符的操作
+func isOpenOperation(operation string) bool {
+	switch operation {
+	case "open", "open_nocancel", "open_extended", "openat", "openat_nocancel", "open_dprotected_np", "guarded_open_np", "create":
+		return true
+	}
+	return false
+}
+
+// isCloseOperation 判断是否为关闭文件描述符的操作
+func isCloseOperation(operation string) bool {
+	switch operation {
+	case "close", "close_nocancel", "guarded_close_np":
+		return true
+	}
+	return false
+}
+
+// fsUsageErrnoRegex 匹配fs_usage输出中的错误码，如 [  2]
+var fsUsageErrnoRegex = regexp.MustCompile(`\[\s*(\d+)\]`)
+
+// parseFsUsageDetails 解析文件描述符、字节数、耗时和错误码等附加信息
+// 典型的输出行：12:00:00.000100  read  F=3  B=0x200  0.000004 W  vim.1234
+func parseFsUsageDetails(line string, fields []string, access *database.FileAccess) {
+	for _, field := range fields[2:] {
+		if value, ok := strings.CutPrefix(field, "F="); ok {
+			if fd, err := strconv.Atoi(value); err == nil {
+				access.FD = fd
+			}
+		} else if value, ok := strings.CutPrefix(field, "B="); ok {
+			if bytes, err := strconv.ParseInt(value, 0, 64); err == nil {
+				access.Bytes = bytes
+			}
+		}
+	}
+
+	// 耗时在进程信息之前，调用发生等待时后面会多一个W标记
+	index := len(fields) - 2
+	if index > 1 && fields[index] == "W" {
+		index--
+	}
+	end := len(fields) - 1
+	if index > 1 && strings.Contains(fields[index], ".") {
+		if seconds, err := strconv.ParseFloat(fields[index], 64); err == nil && seconds >= 0 {
+			access.Duration = time.Duration(seconds * float64(time.Second))
+			end = index
+		}
+	}
+
+	// 错误码只出现在路径和耗时之前，路径中的 [2] 等内容不是错误码
+	if match := fsUsageErrnoRegex.FindStringSubmatch(fsUsageDetailRegion(line, fields, end)); match != nil {
+		access.Errno, _ = strconv.Atoi(match[1])
+	}
+}
+
+// fsUsageDetailRegion 返回输出行中操作类型之后、第一个路径之前的部分，end为耗时字段的序号
+func fsUsageDetailRegion(line string, fields []string, end int) string {
+	start, offset := -1, 0
+	for i, field := range fields[:end] {
+		pos := offset + strings.Index(line[offset:], field)
+		if i == 2 {
+			start = pos
+		}
+		if i >= 2 && strings.HasPrefix(field, "/") {
+			return line[start:pos]
+		}
+		offset = pos + len(field)
+	}
+	if start < 0 {
+		return ""
+	}
+	return line[start:offset]
+}
+
+// parseProcessInfo 从进程信息字符串中提取进程名和PID
+func parseProcessInfo(info string) (string, int) {
+	// 尝试解析进程名和PID (格式通常是 processName.PID)
+	lastDot := strings.LastIndex(info, ".")
+	if lastDot > 0 && lastDot < len(info)-1 {
+		if pid, err := strconv.Atoi(info[lastDot+1:]); err == nil {
+			return info[:lastDot], pid
+		}
+	}
+
+	return info, 0
+}
+
+// extractFilePathSimple 使用简单的字符串方法从输出行中提取文件路径
+func extractFilePathSimple(line string, fields []string) string {
+	// 寻找以/开头的字段，这很可能是文件路径
+	for _, field := range fields {
+		if strings.HasPrefix(field, "/") {
+			return field
+		}
+	}
+
+	// 检查是否有截断的路径（如 ystem/Volumes/）
+	for i, field := range fields {
+		if i > 0 && (strings.Contains(field, "/Volumes/") ||
+			strings.Contains(field, "Library/") ||
+			strings.Contains(field, "/Users/")) {
+
+			// 可能是截断的路径
+			if strings.HasPrefix(field, "ystem/") {
+				return "/S" + field
+			} else if strings.HasPrefix(field, "olumes/") {
+				return "/V" + field
+			} else if strings.HasPrefix(field, "ibrary/") {
+				return "/L" + field
+			} else if strings.HasPrefix(field, "sers/") {
+				return "/U" + field
+			} else {
+				// 其他情况，如果看起来像是路径的一部分，加上前缀
+				return "/" + field
+			}
+		}
+	}
+
+	return ""
+}
+
+// SetFSUsageOptions 设置fs_usage事件源的过滤模式和监控的进程
+func SetFSUsageOptions(options FSUsageOptions) error {
+	options, err := cleanFSUsageOptions(options)
+	if err != nil {
+		return err
+	}
+
+	fsUsageOptions = options
+	if len(options.Modes) > 0 || len(options.Targets) > 0 {
+		log.Printf("已设置fs_usage参数: %s", strings.Join(GetFSUsageArgs(), " "))
+	}
+	return nil
+}
+
+// cleanFSUsageOptions 校验过滤模式和监控目标，去掉空白和重复的模式
+func cleanFSUsageOptions(options FSUsageOptions) (FSUsageOptions, error) {
+	modes := make([]string, 0, len(options.Modes))
+	seen := make(map[string]bool, len(options.Modes))
+	for _, mode := range options.Modes {
+		mode = strings.TrimSpace(mode)
+		if !isValidFSUsageMode(mode) {
+			return FSUsageOptions{}, fmt.Errorf("未知的fs_usage过滤模式: %s，可选值: %s", mode, strings.Join(FSUsageModes, ", "))
+		}
+		if !seen[mode] {
+			seen[mode] = true
+			modes = append(modes, mode)
+		}
+	}
+
+	targets := make([]string, 0, len(options.Targets))
+	for _, target := range options.Targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		// 目标作为命令行参数传给fs_usage，不能被当作选项
+		if strings.HasPrefix(target, "-") || strings.ContainsAny(target, " \t") {
+			return FSUsageOptions{}, fmt.Errorf("无效的fs_usage监控目标: %s", target)
+		}
+		targets = append(targets, target)
+	}
+
+	return FSUsageOptions{Modes: modes, Targets: targets}, nil
+}
+
+// GetFSUsageOptions 获取fs_usage事件源的配置
+func GetFSUsageOptions() FSUsageOptions {
+	return fsUsageOptions
+}
+
+// ResetFSUsageOptions 恢复为只使用filesystem模式监控所有进程
+func ResetFSUsageOptions() {
+	fsUsageOptions = FSUsageOptions{}
+}
+
+// isValidFSUsageMode 判断是否为支持的fs_usage过滤模式
+func isValidFSUsageMode(mode string) bool {
+	for _, m := range FSUsageModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// fsUsageModes 返回当前会话使用的过滤模式
+func fsUsageModes() []string {
+	if len(fsUsageOptions.Modes) == 0 {
+		return []string{FSUsageModeFilesystem}
+	}
+	return fsUsageOptions.Modes
+}
+
+// fsUsageModeEnabled 判断当前会话是否开启了指定的过滤模式
+func fsUsageModeEnabled(mode string) bool {
+	for _, m := range fsUsageModes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFSUsageArgs 返回运行fs_usage时使用的参数（不含sudo）
+func GetFSUsageArgs() []string {
+	return fsUsageArgs(fsUsageOptions)
+}
+
+// fsUsageArgs 返回按指定配置运行fs_usage时使用的参数（不含sudo）
+func fsUsageArgs(options FSUsageOptions) []string {
+	modes := options.Modes
+	if len(modes) == 0 {
+		modes = []string{FSUsageModeFilesystem}
+	}
+
+	args := []string{"fs_usage", "-w"}
+	for _, mode := range modes {
+		args = append(args, "-f", mode)
+	}
+	return append(args, options.Targets...)
+}
+
+// GetFSUsageCommand 返回适合用户执行的fs_usage命令
+func GetFSUsageCommand() string {
+	return "sudo " + strings.Join(GetFSUsageArgs(), " ")
+}
@@ -0,0 +1,162 @@
+package monitor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+func TestParseFsUsageDetailsErrno(t *testing.T) {
+	tests := []struct {
+		line  string
+		errno int
+	}{
+		{"12:00:00.000100  open  F=3  (R_____)  /Users/me/a.txt  0.000012  vim.1234", 0},
+		{"12:00:00.000100  open  [  2]  /Users/me/a.txt  0.000012  vim.1234", 2},
+		{"12:00:00.000100  stat64  [ 13]  /private/var/db  0.000004 W  mds.88", 13},
+		{"12:00:00.000100  read  F=3  [  9]  0.000004  vim.1234", 9},
+		{"12:00:00.000100  open  F=4  (R_____)  /Users/me/report[2].txt  0.000012  vim.1234", 0},
+		{"12:00:00.000100  rename  /Users/me/a  /Users/me/copy [2]  0.000012  Finder.77", 0},
+	}
+
+	for _, tc := range tests {
+		var access database.FileAccess
+		parseFsUsageDetails(tc.line, strings.Fields(tc.line), &access)
+		if access.Errno != tc.errno {
+			t.Errorf("%q: errno = %d, want %d", tc.line, access.Errno, tc.errno)
+		}
+	}
+}
+
+func TestFSUsageParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		modes []string
+		lines []string
+		want  []database.FileAccess
+	}{
+		{
+			name: "descriptor-only lines resolve to the opened path",
+			lines: []string{
+				"12:00:00.000100  open  F=3  (R_____)  /Users/me/a.txt  0.000012  vim.1234",
+				"12:00:00.000200  read  F=3  B=0x200  0.000004 W  vim.1234",
+				"12:00:00.000300  read  F=3  B=0x200  0.000004  vim.999",
+				"12:00:00.000400  close  F=3  0.000002  vim.1234",
+				"12:00:00.000500  read  F=3  B=0x200  0.000004  vim.1234",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "vim", PID: 1234, FilePath: "/Users/me/a.txt", Operation: "open", Category: database.CategoryRead, Mode: FSUsageModeFilesystem, FD: 3},
+				{ProcessName: "vim", PID: 1234, FilePath: "/Users/me/a.txt", Operation: "read", Category: database.CategoryRead, Mode: FSUsageModeFilesystem, FD: 3, Bytes: 0x200},
+				{ProcessName: "vim", PID: 1234, FilePath: "/Users/me/a.txt", Operation: "close", Category: database.CategoryRead, Mode: FSUsageModeFilesystem, FD: 3},
+			},
+		},
+		{
+			name: "open mode decides the category",
+			lines: []string{
+				"12:00:00.000100  open  F=4  (_W____)  /Users/me/b.txt  0.000012  vim.1234",
+				"12:00:00.000200  open  F=5  (RWC___)  /Users/me/c.txt  0.000012  vim.1234",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "vim", PID: 1234, FilePath: "/Users/me/b.txt", Operation: "open", Category: database.CategoryWrite, Mode: FSUsageModeFilesystem, FD: 4},
+				{ProcessName: "vim", PID: 1234, FilePath: "/Users/me/c.txt", Operation: "open", Category: database.CategoryCreate, Mode: FSUsageModeFilesystem, FD: 5},
+			},
+		},
+		{
+			name:  "diskio lines use the file path after the device",
+			modes: []string{FSUsageModeDiskIO},
+			lines: []string{
+				"12:00:00.000100  RdData[A]  D=0x0012a000  B=0x1000  /dev/disk1s1  /Users/me/file  0.000200 W  mds.123",
+				"12:00:00.000200  WrData[AT]  D=0x00000200  B=0x2000  /dev/disk1s1  /Users/me/out.log  0.000100  kernel_task.0",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "mds", PID: 123, FilePath: "/Users/me/file", Operation: "RdData[A]", Category: database.CategoryRead, Mode: FSUsageModeDiskIO, Bytes: 0x1000},
+				{ProcessName: "kernel_task", FilePath: "/Users/me/out.log", Operation: "WrData[AT]", Category: database.CategoryWrite, Mode: FSUsageModeDiskIO, Bytes: 0x2000},
+			},
+		},
+		{
+			name:  "cachehit lines",
+			modes: []string{FSUsageModeCacheHit},
+			lines: []string{
+				"12:00:00.000100  CACHE_HIT  D=0x0012a000  B=0x1000  /dev/disk1s1  /Users/me/lib.dylib  0.000001  Xcode.42",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "Xcode", PID: 42, FilePath: "/Users/me/lib.dylib", Operation: "CACHE_HIT", Category: database.CategoryRead, Mode: FSUsageModeCacheHit, Bytes: 0x1000},
+			},
+		},
+		{
+			name:  "exec lines are kept for the process table",
+			modes: []string{FSUsageModeExec},
+			lines: []string{
+				"12:00:00.000100  execve  /bin/ls  0.000300  zsh.77",
+				"12:00:00.000200  posix_spawn  /usr/bin/git  0.000300  zsh.77",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "zsh", PID: 77, FilePath: "/bin/ls", Operation: "execve", Category: database.CategoryExec, Mode: FSUsageModeExec},
+				{ProcessName: "zsh", PID: 77, FilePath: "/usr/bin/git", Operation: "posix_spawn", Category: database.CategoryExec, Mode: FSUsageModeExec},
+			},
+		},
+		{
+			name:  "pathname lines are attributed to pathname mode",
+			modes: []string{FSUsageModePathname},
+			lines: []string{
+				"12:00:00.000100  stat64  /Users/me/d.txt  0.000004  ls.88",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "ls", PID: 88, FilePath: "/Users/me/d.txt", Operation: "stat64", Category: database.CategoryMetadata, Mode: FSUsageModePathname},
+			},
+		},
+		{
+			name:  "filesystem wins when both filesystem and pathname are enabled",
+			modes: []string{FSUsageModeFilesystem, FSUsageModePathname},
+			lines: []string{
+				"12:00:00.000100  read  F=3  B=0x10  /Users/me/e.txt  0.000004  cat.90",
+			},
+			want: []database.FileAccess{
+				{ProcessName: "cat", PID: 90, FilePath: "/Users/me/e.txt", Operation: "read", Category: database.CategoryRead, Mode: FSUsageModeFilesystem, FD: 3, Bytes: 0x10},
+			},
+		},
+	}
+
+	defer ResetFSUsageOptions()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetFSUsageOptions(FSUsageOptions{Modes: tc.modes}); err != nil {
+				t.Fatal(err)
+			}
+			parser := newFSUsageParser(time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local))
+			var got []database.FileAccess
+			for _, line := range tc.lines {
+				if access := parser.parseLine(line); access != nil {
+					access.Timestamp = time.Time{}
+					access.Duration = 0
+					got = append(got, *access)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFSUsageParserFDEviction(t *testing.T) {
+	parser := newFSUsageParser(time.Now())
+	for fd := 0; fd < maxTrackedFDs; fd++ {
+		parser.fds[fdKey{pid: 1, fd: fd}] = "/Users/me/old"
+	}
+
+	parser.parseLine("12:00:00.000100  open  F=7  (R_____)  /Users/me/new.txt  0.000012  vim.2")
+	if len(parser.fds) != 1 {
+		t.Fatalf("tracked descriptors = %d after eviction, want 1", len(parser.fds))
+	}
+	if access := parser.parseLine("12:00:00.000200  read  F=3  B=0x10  0.000004  vim.1"); access != nil {
+		t.Errorf("evicted descriptor resolved to %q", access.FilePath)
+	}
+	access := parser.parseLine("12:00:00.000300  read  F=7  B=0x10  0.000004  vim.2")
+	if access == nil || access.FilePath != "/Users/me/new.txt" {
+		t.Errorf("descriptor opened after eviction = %+v, want /Users/me/new.txt", access)
+	}
+}
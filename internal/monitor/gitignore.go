@@ -0,0 +1,303 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// gitignoreFileName 目录中的忽略文件名，指定目录时递归查找
+const gitignoreFileName = ".gitignore"
+
+// gitignoreRule 忽略文件中的一条规则
+//
+// 规则的语法与git相同：
+//   - 空行和以 # 开头的行被忽略，\# 和 \! 表示以 # 或 ! 开头的模式
+//   - 以 ! 开头表示取反，重新包含之前被忽略的路径，但上级目录被忽略时无法重新包含
+//   - 以 / 结尾只匹配目录，目录下的所有文件随之被忽略
+//   - 开头或中间包含 / 时相对于忽略文件所在的目录匹配，否则匹配任意层级的名称
+//   - * 和 ? 不匹配 /，**/ 匹配任意层级的目录，/** 匹配目录下的所有内容
+type gitignoreRule struct {
+	source   string   // 规则所在的文件和行号，用于日志
+	base     []string // 忽略文件所在目录的路径段
+	segments []string // 模式的路径段，** 匹配零个或多个路径段
+	negate   bool
+	dirOnly  bool
+}
+
+// gitignoreMatcher 一个会话加载的所有忽略文件
+// 规则按忽略文件所在目录的层级排序，深层目录的规则在后，同一路径以最后一条匹配的规则为准
+type gitignoreMatcher struct {
+	files []string
+	rules []gitignoreRule
+}
+
+// SetIgnoreFiles 设置会话使用的gitignore文件，可以指定文件或目录，目录下的所有 .gitignore 都会被加载
+// 文件在设置时读取一次，文件读取失败时返回错误且不修改当前设置
+func SetIgnoreFiles(paths ...string) error {
+	paths = cleanPatterns(paths)
+	if len(paths) == 0 {
+		ResetIgnoreFiles()
+		return nil
+	}
+
+	matcher, err := loadGitignore(paths)
+	if err != nil {
+		return err
+	}
+
+	updateFilters(func(f *filterSet) { f.gitignore, f.ignoreFiles = matcher, paths })
+	log.Printf("已加载 %d 个忽略文件，共 %d 条规则: %s", len(matcher.files), len(matcher.rules), strings.Join(matcher.files, ", "))
+	return nil
+}
+
+// GetIgnoreFiles 获取当前会话引用的忽略文件或目录
+func GetIgnoreFiles() []string {
+	return append([]string{}, currentFilters().ignoreFiles...)
+}
+
+// ResetIgnoreFiles 重置会话引用的忽略文件
+func ResetIgnoreFiles() {
+	updateFilters(func(f *filterSet) { f.gitignore, f.ignoreFiles = nil, nil })
+	log.Println("已重置忽略文件")
+}
+
+// loadGitignore 加载忽略文件，目录中递归查找 .gitignore，跳过已被忽略的子目录
+func loadGitignore(paths []string) (*gitignoreMatcher, error) {
+	m := &gitignoreMatcher{}
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("无效的忽略文件路径 '%s': %w", p, err)
+		}
+
+		// 事件中的路径已解析符号链接（如macOS的/tmp为/private/tmp），规则的目录同样按真实路径匹配
+		if abs, err = filepath.EvalSymlinks(abs); err != nil {
+			return nil, fmt.Errorf("读取忽略文件失败: %w", err)
+		}
+
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("读取忽略文件失败: %w", err)
+		}
+
+		if !info.IsDir() {
+			if err := m.addFile(abs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(abs, func(dir string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// 无法读取的子目录直接跳过
+				if dir == abs {
+					return err
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if dir != abs && (d.Name() == ".git" || m.match(dir, true)) {
+				return filepath.SkipDir
+			}
+
+			file := filepath.Join(dir, gitignoreFileName)
+			if _, err := os.Stat(file); err != nil {
+				return nil
+			}
+			return m.addFile(file)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("查找忽略文件失败: %w", err)
+		}
+	}
+
+	// 上级目录的规则在前，深层目录的规则可以覆盖上级目录的规则
+	sort.SliceStable(m.rules, func(i, j int) bool {
+		return len(m.rules[i].base) < len(m.rules[j].base)
+	})
+	return m, nil
+}
+
+// addFile 读取一个忽略文件的规则，相对于文件所在的目录匹配
+func (m *gitignoreMatcher) addFile(file string) error {
+	for _, loaded := range m.files {
+		if loaded == file {
+			return nil
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("读取忽略文件失败: %w", err)
+	}
+	defer f.Close()
+
+	base := splitPathSegments(filepath.ToSlash(filepath.Dir(file)))
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		source := fmt.Sprintf("%s:%d", file, n)
+		rule, ok, err := parseGitignoreLine(scanner.Text())
+		if err != nil {
+			// 与git相同，无效的模式不影响其他规则
+			log.Printf("忽略无效的规则 %s: %v", source, err)
+			continue
+		}
+		if ok {
+			rule.source = source
+			rule.base = base
+			m.rules = append(m.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取忽略文件失败: %w", err)
+	}
+
+	m.files = append(m.files, file)
+	return nil
+}
+
+// parseGitignoreLine 解析忽略文件中的一行，空行和注释返回false
+func parseGitignoreLine(line string) (gitignoreRule, bool, error) {
+	var rule gitignoreRule
+
+	// 去掉行尾的空格，以 \ 转义的空格保留
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+
+	// 开头或中间包含 / 的模式相对于忽略文件所在的目录，否则匹配任意层级
+	anchored := strings.Contains(line, "/")
+	if !anchored {
+		rule.segments = append(rule.segments, "**")
+	}
+	for _, segment := range splitPathSegments(line) {
+		if segment == "**" {
+			if len(rule.segments) == 0 || rule.segments[len(rule.segments)-1] != "**" {
+				rule.segments = append(rule.segments, segment)
+			}
+			continue
+		}
+
+		segment = convertGlobSegment(segment)
+		if _, err := path.Match(segment, ""); err != nil {
+			return rule, false, fmt.Errorf("模式 '%s' 无效: %w", line, err)
+		}
+		rule.segments = append(rule.segments, segment)
+	}
+
+	// 结尾的 /** 匹配目录下的所有内容，至少需要一个路径段
+	if n := len(rule.segments); anchored && n > 0 && rule.segments[n-1] == "**" {
+		rule.segments = append(rule.segments[:n-1], "*", "**")
+	}
+
+	return rule, true, nil
+}
+
+// match 判断路径是否被忽略，上级目录被忽略时其中的路径同样被忽略
+// isDir 表示路径是否为目录，由事件给出，匹配时不查询文件系统，无法区分时目录规则只匹配上级目录
+func (m *gitignoreMatcher) match(p string, isDir bool) bool {
+	return m.matchRule(p, isDir) != nil
+}
+
+// matchRule 返回忽略路径的规则，路径没有被忽略时返回nil
+func (m *gitignoreMatcher) matchRule(p string, isDir bool) *gitignoreRule {
+	parts := splitPathSegments(filepath.ToSlash(p))
+	for i := 1; i < len(parts); i++ {
+		if rule := m.ignored(parts[:i], true); rule != nil {
+			return rule
+		}
+	}
+	return m.ignored(parts, isDir)
+}
+
+// ignored 按顺序应用所有规则，路径被忽略时返回忽略该路径的规则
+func (m *gitignoreMatcher) ignored(parts []string, isDir bool) *gitignoreRule {
+	var ignoredBy *gitignoreRule
+	for i := range m.rules {
+		rule := &m.rules[i]
+		if (ignoredBy != nil) != rule.negate {
+			// 当前结果与规则的效果相同时无需匹配
+			continue
+		}
+
+		rel, ok := cutPathSegments(parts, rule.base)
+		if !ok || !matchGitignoreSegments(rule.segments, rel) {
+			continue
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		ignoredBy = nil
+		if !rule.negate {
+			ignoredBy = rule
+		}
+	}
+	return ignoredBy
+}
+
+// cutPathSegments 返回路径相对于目录的路径段，路径不在目录下时返回false
+func cutPathSegments(parts []string, base []string) ([]string, bool) {
+	if len(parts) <= len(base) {
+		return nil, false
+	}
+	for i, segment := range base {
+		if parts[i] != segment {
+			return nil, false
+		}
+	}
+	return parts[len(base):], true
+}
+
+// matchGitignoreSegments 逐段匹配，与通配符不同，模式需要匹配完整的路径
+func matchGitignoreSegments(segments []string, parts []string) bool {
+	if len(segments) == 0 {
+		return len(parts) == 0
+	}
+
+	if segments[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGitignoreSegments(segments[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(segments[0], parts[0]); !matched {
+		return false
+	}
+	return matchGitignoreSegments(segments[1:], parts[1:])
+}
@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignoreMatch(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore": `# 构建产物
+node_modules
+target/
+*.log
+!important.log
+/dist
+docs/**/*.tmp
+build/**
+\#notes
+` + "trailing.txt   \n" + `\!bang
+logs/
+!logs/keep.txt
+`,
+		"pkg/.gitignore": `*.gen.go
+!keep.log
+/local
+`,
+	}
+
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := loadGitignore([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.files) != 2 {
+		t.Fatalf("loaded %d files, want 2: %v", len(m.files), m.files)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		// 不含 / 的模式匹配任意层级
+		{"node_modules", true},
+		{"node_modules/react/index.js", true},
+		{"src/node_modules/a.js", true},
+		{"src/app.js", false},
+
+		// 目录规则只匹配目录及其中的内容，路径本身是否为目录由事件给出
+		{"target", false},
+		{"target/debug/app", true},
+		{"src/target/x.o", true},
+		{"src/target.rs", false},
+
+		// 取反
+		{"app.log", true},
+		{"src/app.log", true},
+		{"important.log", false},
+		{"src/important.log", false},
+
+		// 上级目录被忽略时无法重新包含
+		{"logs/keep.txt", true},
+
+		// 以 / 开头的模式相对于忽略文件所在的目录
+		{"dist", true},
+		{"dist/app.js", true},
+		{"src/dist/app.js", false},
+
+		// **
+		{"docs/a.tmp", true},
+		{"docs/a/b/c.tmp", true},
+		{"docs/a/b/c.md", false},
+		{"build", false},
+		{"build/out/a", true},
+
+		// 转义和行尾空格
+		{"#notes", true},
+		{"!bang", true},
+		{"trailing.txt", true},
+
+		// 子目录中的忽略文件只作用于子目录，并可以覆盖上级目录的规则
+		{"pkg/api.gen.go", true},
+		{"api.gen.go", false},
+		{"pkg/keep.log", false},
+		{"pkg/sub/keep.log", false},
+		{"keep.log", true},
+		{"pkg/local/a", true},
+		{"pkg/sub/local/a", false},
+
+		// 忽略文件所在目录以外的路径
+		{"../outside.log", false},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(root, tt.path)
+		if got := m.match(path, false); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, dir := range []string{"target", "pkg/target", "logs"} {
+		if !m.match(filepath.Join(root, dir), true) {
+			t.Errorf("match(%q, isDir) = false, want true", dir)
+		}
+	}
+}
+
+func TestGitignoreSkipsIgnoredDirectories(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":                   "vendor/\n",
+		"vendor/lib/.gitignore":        "*.go\n",
+		"src/.gitignore":               "*.out\n",
+		".git/modules/x/.gitignore":    "*\n",
+		"src/deep/nested/.gitignore":   "!a.out\n",
+		"src/deep/nested/sub/.keep":    "",
+		"vendor/lib/not-ignored-by.go": "",
+	} {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := loadGitignore([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.files) != 3 {
+		t.Errorf("loaded %d files, want 3: %v", len(m.files), m.files)
+	}
+
+	if !m.match(filepath.Join(root, "src/b.out"), false) {
+		t.Error("src/b.out should be ignored")
+	}
+	if m.match(filepath.Join(root, "src/deep/nested/a.out"), false) {
+		t.Error("src/deep/nested/a.out should be re-included by the nested file")
+	}
+}
@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// globPattern 编译后的通配符模式
+//
+// 路径模式按 / 拆分为路径段逐段匹配：
+//   - *       匹配路径段内任意数量的字符，不跨越 /
+//   - **      作为完整的路径段时匹配零个或多个路径段，否则等同于 *
+//   - ?       匹配路径段内的单个字符
+//   - [...]   匹配字符集中的一个字符，支持范围 a-z，以 ! 或 ^ 开头表示取反
+//   - {a,b}   匹配任意一个候选，候选中可以包含 / 和其他通配符，支持嵌套
+//   - \       转义下一个字符
+//
+// 不以 / 开头的路径模式可以匹配任意层级，等同于在前面加上 **/
+// 模式匹配某个目录时，该目录下的所有文件也视为匹配，如 /src 和 /src/* 都匹配 /src/a/b.go
+type globPattern struct {
+	pattern      string
+	alternatives [][]string // 展开花括号后的各个候选，每个候选为路径段列表
+	pathMode     bool       // 路径模式，名称模式（如进程名）不拆分路径段，也不匹配子路径
+}
+
+// compilePathGlob 编译用于匹配文件路径的通配符模式
+func compilePathGlob(pattern string) (*globPattern, error) {
+	return compileGlob(pattern, true)
+}
+
+// compileNameGlob 编译用于匹配进程名等名称的通配符模式，整个名称按单个路径段匹配
+func compileNameGlob(pattern string) (*globPattern, error) {
+	return compileGlob(pattern, false)
+}
+
+// compileGlob 编译通配符模式，模式无效时返回错误
+func compileGlob(pattern string, pathMode bool) (*globPattern, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("通配符模式不能为空")
+	}
+
+	expanded, err := expandBraces(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("通配符模式 '%s' 无效: %w", pattern, err)
+	}
+
+	g := &globPattern{pattern: pattern, pathMode: pathMode}
+	for _, alternative := range expanded {
+		var segments []string
+		if pathMode {
+			segments = splitGlobSegments(alternative)
+		} else {
+			// 名称中不区分路径段，** 与 * 含义相同
+			segments = []string{strings.ReplaceAll(alternative, "**", "*")}
+		}
+
+		for i, segment := range segments {
+			if segment == "**" {
+				continue
+			}
+			segment = convertGlobSegment(segment)
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("通配符模式 '%s' 无效: %w", pattern, err)
+			}
+			segments[i] = segment
+		}
+		g.alternatives = append(g.alternatives, segments)
+	}
+
+	return g, nil
+}
+
+// String 返回原始的通配符模式
+func (g *globPattern) String() string {
+	return g.pattern
+}
+
+// match 判断字符串是否匹配通配符模式
+func (g *globPattern) match(value string) bool {
+	if !g.pathMode {
+		for _, segments := range g.alternatives {
+			if matched, _ := path.Match(segments[0], value); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	parts := splitPathSegments(value)
+	for _, segments := range g.alternatives {
+		if matchGlobSegments(segments, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGlobSegments 将路径模式拆分为路径段，相对模式在前面加上 **
+func splitGlobSegments(pattern string) []string {
+	absolute := strings.HasPrefix(pattern, "/")
+	segments := splitPathSegments(pattern)
+
+	// 合并连续的 **
+	var result []string
+	if !absolute {
+		result = append(result, "**")
+	}
+	for _, segment := range segments {
+		if segment == "**" && len(result) > 0 && result[len(result)-1] == "**" {
+			continue
+		}
+		result = append(result, segment)
+	}
+	return result
+}
+
+// splitPathSegments 按 / 拆分路径，忽略空的路径段
+func splitPathSegments(p string) []string {
+	var segments []string
+	for _, segment := range strings.Split(p, "/") {
+		if segment != "" && segment != "." {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// convertGlobSegment 将路径段转换为path.Match的语法
+// [!...] 转换为 [^...]，不是完整路径段的 ** 等同于 *
+func convertGlobSegment(segment string) string {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch {
+		case c == '\\' && i+1 < len(segment):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(segment[i])
+			continue
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+			if i+1 < len(segment) && segment[i+1] == '!' {
+				b.WriteByte('^')
+				i++
+			}
+			continue
+		case c == '*':
+			for i+1 < len(segment) && segment[i+1] == '*' {
+				i++
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// matchGlobSegments 逐段匹配，模式的路径段用完时剩余的路径位于匹配的目录之下，同样视为匹配
+func matchGlobSegments(segments []string, parts []string) bool {
+	if len(segments) == 0 {
+		return true
+	}
+
+	if segments[0] == "**" {
+		// ** 匹配零个或多个路径段
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobSegments(segments[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(segments[0], parts[0]); !matched {
+		return false
+	}
+	return matchGlobSegments(segments[1:], parts[1:])
+}
+
+// maxBraceExpansions 花括号展开后的候选数量上限，避免多组花括号组合后数量过多
+const maxBraceExpansions = 1024
+
+// expandBraces 展开模式中的花括号，返回所有候选模式，候选超过 maxBraceExpansions 个时返回错误
+func expandBraces(pattern string) ([]string, error) {
+	start, end, err := findBraces(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		return []string{pattern}, nil
+	}
+
+	prefix, body, suffix := pattern[:start], pattern[start+1:end], pattern[end+1:]
+
+	var result []string
+	for _, option := range splitBraceOptions(body) {
+		expanded, err := expandBraces(prefix + option + suffix)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+		if len(result) > maxBraceExpansions {
+			return nil, fmt.Errorf("花括号展开后超过 %d 个候选", maxBraceExpansions)
+		}
+	}
+	return result, nil
+}
+
+// findBraces 返回第一组顶层花括号的位置，没有花括号时返回-1
+func findBraces(pattern string) (int, int, error) {
+	start, depth := -1, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				return -1, -1, fmt.Errorf("位置 %d 的 } 没有对应的 {", i)
+			}
+			depth--
+			if depth == 0 {
+				return start, i, nil
+			}
+		}
+	}
+
+	if depth > 0 {
+		return -1, -1, fmt.Errorf("位置 %d 的 { 没有对应的 }", start)
+	}
+	return -1, -1, nil
+}
+
+// splitBraceOptions 按顶层逗号拆分花括号中的候选
+func splitBraceOptions(body string) []string {
+	var options []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				options = append(options, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(options, body[start:])
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import "testing"
+
+func TestPathGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// 普通路径和目录前缀
+		{"/Users/me/src", "/Users/me/src", true},
+		{"/Users/me/src", "/Users/me/src/a/b.go", true},
+		{"/Users/me/src", "/Users/me/srcx/b.go", false},
+		{"/Users/me/src/", "/Users/me/src/b.go", true},
+		{"/", "/etc/hosts", true},
+
+		// * 只匹配一个路径段
+		{"/Users/*.go", "/Users/main.go", true},
+		{"/Users/*.go", "/Users/me/main.go", false},
+		{"/Users/*/src", "/Users/me/src/main.go", true},
+		{"/Users/*/src", "/Users/me/work/src", false},
+		{"/Users/*", "/Users/me/src/main.go", true},
+		{"/Users/me*", "/Users/me2/a", true},
+		{"/Users/a**b", "/Users/axyb", true},
+		{"/Users/a**b", "/Users/ax/yb", false},
+
+		// ** 匹配零个或多个路径段
+		{"/src/**/*.js", "/src/app.js", true},
+		{"/src/**/*.js", "/src/a/b/c/app.js", true},
+		{"/src/**/*.js", "/lib/app.js", false},
+		{"/src/**", "/src", true},
+		{"/src/**", "/src/a/b", true},
+		{"/a/**/b/**/c", "/a/b/c", true},
+		{"/a/**/b/**/c", "/a/x/b/y/z/c", true},
+		{"/a/**/b/**/c", "/a/x/y/c", false},
+		{"/a/**/**/c", "/a/c", true},
+		{"/Users/*/src/**", "/Users/me/src/x/y.go", true},
+
+		// ? 匹配单个字符
+		{"/tmp/?.txt", "/tmp/a.txt", true},
+		{"/tmp/?.txt", "/tmp/ab.txt", false},
+		{"/tmp/?", "/tmp/a/b", true},
+
+		// 字符集
+		{"/log/[abc].log", "/log/b.log", true},
+		{"/log/[abc].log", "/log/d.log", false},
+		{"/log/[a-c].log", "/log/c.log", true},
+		{"/log/[!a-c].log", "/log/d.log", true},
+		{"/log/[!a-c].log", "/log/a.log", false},
+		{"/log/[^a-c].log", "/log/a.log", false},
+
+		// 花括号
+		{"/src/*.{go,js}", "/src/main.go", true},
+		{"/src/*.{go,js}", "/src/main.js", true},
+		{"/src/*.{go,js}", "/src/main.py", false},
+		{"/{etc,var/lib}/*.conf", "/var/lib/a.conf", true},
+		{"/{etc,var/lib}/*.conf", "/var/a.conf", false},
+		{"/a/{b,{c,d}x}/e", "/a/dx/e", true},
+		{"/a/{b,{c,d}x}/e", "/a/d/e", false},
+		{"/a/{,b/}c", "/a/c", true},
+		{"/a/{,b/}c", "/a/b/c", true},
+
+		// 转义
+		{`/a/\*`, "/a/*", true},
+		{`/a/\*`, "/a/b", false},
+		{`/a/\{b,c\}`, "/a/{b,c}", true},
+
+		// 相对模式匹配任意层级
+		{"*.git", "/repo/.git", true},
+		{".git", "/repo/.git/config", true},
+		{"*.swp", "/home/me/.main.go.swp", true},
+		{"node_modules", "/app/node_modules/react/index.js", true},
+		{"*/node_modules/*", "/app/node_modules/react/index.js", true},
+		{"*/node_modules/*", "/node_modules/react", false},
+		{"src/*.go", "/home/me/src/main.go", true},
+		{"src/*.go", "/home/me/src/pkg/main.go", false},
+		{"src/*.go", "/home/me/lib/main.go", false},
+	}
+
+	for _, tt := range tests {
+		glob, err := compilePathGlob(tt.pattern)
+		if err != nil {
+			t.Errorf("compilePathGlob(%q) error: %v", tt.pattern, err)
+			continue
+		}
+		if got := glob.match(tt.path); got != tt.want {
+			t.Errorf("%q match %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNameGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"Chrome*", "Chrome Helper", true},
+		{"Chrome*", "Google Chrome", false},
+		{"*java*", "openjdk-java", true},
+		{"vim", "vim", true},
+		{"vim", "nvim", false},
+		{"?vim", "nvim", true},
+		{"{vim,nvim}", "nvim", true},
+		{"[a-c]at", "cat", true},
+		{"[!a-c]at", "rat", true},
+		{"**", "vim", true},
+	}
+
+	for _, tt := range tests {
+		glob, err := compileNameGlob(tt.pattern)
+		if err != nil {
+			t.Errorf("compileNameGlob(%q) error: %v", tt.pattern, err)
+			continue
+		}
+		if got := glob.match(tt.name); got != tt.want {
+			t.Errorf("%q match %q = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGlobCompileErrors(t *testing.T) {
+	patterns := []string{
+		"",
+		"/a/{b,c",
+		"/a/b}",
+		"/a/[b",
+		"/a/[]",
+		"/{a,b}{c,d}{e,f}{g,h}{i,j}{k,l}{m,n}{o,p}{q,r}{s,t}{u,v}",
+	}
+
+	for _, pattern := range patterns {
+		if _, err := compilePathGlob(pattern); err == nil {
+			t.Errorf("compilePathGlob(%q) expected error", pattern)
+		}
+	}
+}
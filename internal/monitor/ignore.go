@@ -0,0 +1,336 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 忽略规则的类型
+const (
+	IgnoreRulePrefix    = "prefix"    // 路径前缀，如 /private/tmp/
+	IgnoreRuleExtension = "extension" // 路径后缀，如 .swp、.DS_Store
+)
+
+// 内置的忽略规则集名称
+const (
+	DefaultIgnoreProfile = "default" // 未指定规则集时使用，删除后恢复内置规则
+	NoIgnoreProfile      = "none"    // 不使用任何规则集，不能创建或修改
+)
+
+// IgnoreProfile 一组命名的忽略规则，路径匹配任意一条规则时不记录
+type IgnoreProfile struct {
+	Name       string   `json:"name"`
+	Prefixes   []string `json:"prefixes"`   // 忽略的路径前缀，需要以 / 开头
+	Extensions []string `json:"extensions"` // 忽略的扩展名，匹配路径的结尾
+}
+
+// IgnoreOptions 监控会话的忽略规则设置
+type IgnoreOptions struct {
+	Profile    string   `json:"profile"`    // 使用的规则集，为空时使用default，none表示不使用规则集
+	Prefixes   []string `json:"prefixes"`   // 本次会话额外忽略的路径前缀
+	Extensions []string `json:"extensions"` // 本次会话额外忽略的扩展名
+	Allow      []string `json:"allow"`      // 本次会话不使用的规则，填写规则集中的前缀或扩展名，如 /tmp/、.git
+}
+
+// IgnoreRuleStats 忽略规则及本次会话中被其忽略的事件数
+type IgnoreRuleStats struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Suppressed int64  `json:"suppressed"`
+}
+
+// ignoreRule 编译后的忽略规则，计数器在会话内的规则更新之间保留
+type ignoreRule struct {
+	kind       string
+	value      string
+	suppressed *atomic.Int64
+}
+
+// match 判断路径是否匹配忽略规则
+func (r *ignoreRule) match(path string) bool {
+	if r.kind == IgnoreRulePrefix {
+		return strings.HasPrefix(path, r.value)
+	}
+	return strings.HasSuffix(path, r.value)
+}
+
+// defaultIgnoreProfile 返回内置的默认规则集，忽略系统目录和临时文件
+func defaultIgnoreProfile() *IgnoreProfile {
+	return &IgnoreProfile{
+		Name: DefaultIgnoreProfile,
+		Prefixes: []string{
+			"/dev/",
+			"/usr/share/",
+			"/private/var/folders/",
+			"/System/Library/",
+			"/Library/Caches/",
+			"/Library/Logs/",
+			"/var/log/",
+			"/var/db/",
+			"/private/tmp/",
+			"/tmp/",
+			"/Library/Apple/",
+			"/Library/PrivilegedHelperTools/",
+			"/Applications/Xcode.app/Contents/",
+		},
+		Extensions: []string{
+			".tmp",
+			".temp",
+			".cache",
+			".swap",
+			".swp",
+			".DS_Store",
+			".localized",
+			".git",
+		},
+	}
+}
+
+// ignoreProfileNameRegex 规则集名称只允许字母、数字、下划线、点和短横线
+var ignoreProfileNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+var (
+	// 全局变量，用于存储所有的忽略规则集
+	ignoreProfiles = map[string]*IgnoreProfile{DefaultIgnoreProfile: defaultIgnoreProfile()}
+
+	// 全局变量，用于存储当前会话的忽略规则设置
+	ignoreOptions IgnoreOptions
+
+	// 全局变量，用于存储本次会话各条规则的计数器，键为 类型:值
+	ignoreCounters = make(map[string]*atomic.Int64)
+
+	ignoreMu sync.Mutex
+)
+
+func init() {
+	applyIgnoreRules()
+}
+
+// GetIgnoreProfiles 获取所有的忽略规则集，按名称排序
+func GetIgnoreProfiles() []IgnoreProfile {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+
+	names := make([]string, 0, len(ignoreProfiles))
+	for name := range ignoreProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profiles := make([]IgnoreProfile, 0, len(names))
+	for _, name := range names {
+		profiles = append(profiles, copyIgnoreProfile(ignoreProfiles[name]))
+	}
+	return profiles
+}
+
+// GetIgnoreProfile 获取指定名称的忽略规则集
+func GetIgnoreProfile(name string) (IgnoreProfile, bool) {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+
+	profile, ok := ignoreProfiles[name]
+	if !ok {
+		return IgnoreProfile{}, false
+	}
+	return copyIgnoreProfile(profile), true
+}
+
+// SaveIgnoreProfile 创建或替换忽略规则集，当前会话使用该规则集时立即生效
+func SaveIgnoreProfile(profile IgnoreProfile) (IgnoreProfile, error) {
+	if !ignoreProfileNameRegex.MatchString(profile.Name) {
+		return IgnoreProfile{}, fmt.Errorf("无效的规则集名称: '%s'", profile.Name)
+	}
+	if profile.Name == NoIgnoreProfile {
+		return IgnoreProfile{}, fmt.Errorf("规则集 %s 为保留名称，表示不使用规则集", NoIgnoreProfile)
+	}
+
+	prefixes, extensions, err := cleanIgnoreRules(profile.Prefixes, profile.Extensions)
+	if err != nil {
+		return IgnoreProfile{}, err
+	}
+	saved := &IgnoreProfile{Name: profile.Name, Prefixes: prefixes, Extensions: extensions}
+
+	ignoreMu.Lock()
+	ignoreProfiles[saved.Name] = saved
+	ignoreMu.Unlock()
+
+	log.Printf("已保存忽略规则集 %s: %d 个前缀，%d 个扩展名", saved.Name, len(prefixes), len(extensions))
+	applyIgnoreRules()
+	return copyIgnoreProfile(saved), nil
+}
+
+// DeleteIgnoreProfile 删除忽略规则集，删除default时恢复内置规则
+// 当前会话使用的规则集被删除后，会话使用default规则集
+func DeleteIgnoreProfile(name string) error {
+	ignoreMu.Lock()
+	if _, ok := ignoreProfiles[name]; !ok {
+		ignoreMu.Unlock()
+		return fmt.Errorf("规则集 %s 不存在", name)
+	}
+	if name == DefaultIgnoreProfile {
+		ignoreProfiles[name] = defaultIgnoreProfile()
+	} else {
+		delete(ignoreProfiles, name)
+	}
+	ignoreMu.Unlock()
+
+	log.Printf("已删除忽略规则集 %s", name)
+	applyIgnoreRules()
+	return nil
+}
+
+// SetIgnoreOptions 设置本次会话的忽略规则，并清空规则的计数器
+func SetIgnoreOptions(options IgnoreOptions) error {
+	ignoreMu.Lock()
+	options, err := cleanIgnoreOptions(options)
+	if err != nil {
+		ignoreMu.Unlock()
+		return err
+	}
+	ignoreOptions = options
+	ignoreCounters = make(map[string]*atomic.Int64)
+	ignoreMu.Unlock()
+
+	applyIgnoreRules()
+	log.Printf("已设置忽略规则集: %s", options.Profile)
+	return nil
+}
+
+// cleanIgnoreOptions 整理并校验会话的忽略规则设置，调用方需要持有ignoreMu
+func cleanIgnoreOptions(options IgnoreOptions) (IgnoreOptions, error) {
+	options.Profile = strings.TrimSpace(options.Profile)
+	if options.Profile == "" {
+		options.Profile = DefaultIgnoreProfile
+	}
+
+	prefixes, extensions, err := cleanIgnoreRules(options.Prefixes, options.Extensions)
+	if err != nil {
+		return options, err
+	}
+	options.Prefixes, options.Extensions = prefixes, extensions
+	options.Allow = cleanPatterns(options.Allow)
+
+	if _, ok := ignoreProfiles[options.Profile]; !ok && options.Profile != NoIgnoreProfile {
+		return options, fmt.Errorf("忽略规则集 %s 不存在", options.Profile)
+	}
+	return options, nil
+}
+
+// GetIgnoreOptions 获取本次会话的忽略规则设置
+func GetIgnoreOptions() IgnoreOptions {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+
+	options := ignoreOptions
+	if options.Profile == "" {
+		options.Profile = DefaultIgnoreProfile
+	}
+	return options
+}
+
+// ResetIgnoreOptions 重置会话的忽略规则设置为default规则集，计数器保留到下次会话开始
+func ResetIgnoreOptions() {
+	ignoreMu.Lock()
+	ignoreOptions = IgnoreOptions{}
+	ignoreMu.Unlock()
+
+	applyIgnoreRules()
+}
+
+// GetIgnoreStats 获取当前生效的忽略规则及本次会话中被每条规则忽略的事件数
+func GetIgnoreStats() []IgnoreRuleStats {
+	rules := currentFilters().ignore
+	stats := make([]IgnoreRuleStats, 0, len(rules))
+	for _, rule := range rules {
+		stats = append(stats, IgnoreRuleStats{
+			Type:       rule.kind,
+			Value:      rule.value,
+			Suppressed: rule.suppressed.Load(),
+		})
+	}
+	return stats
+}
+
+// applyIgnoreRules 根据会话设置和规则集重新编译忽略规则
+func applyIgnoreRules() {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+
+	rules := buildIgnoreRules(ignoreOptions, ignoreCounters)
+	updateFilters(func(f *filterSet) { f.ignore = rules })
+}
+
+// buildIgnoreRules 根据会话设置和规则集编译忽略规则，counters中没有的规则添加新的计数器，调用方需要持有ignoreMu
+func buildIgnoreRules(options IgnoreOptions, counters map[string]*atomic.Int64) []*ignoreRule {
+	profile := &IgnoreProfile{}
+	if options.Profile != NoIgnoreProfile {
+		name := options.Profile
+		if _, ok := ignoreProfiles[name]; !ok {
+			name = DefaultIgnoreProfile
+		}
+		profile = ignoreProfiles[name]
+	}
+
+	allowed := make(map[string]bool, len(options.Allow))
+	for _, value := range options.Allow {
+		allowed[value] = true
+	}
+
+	var rules []*ignoreRule
+	seen := make(map[string]bool)
+	add := func(kind string, values []string) {
+		for _, value := range values {
+			key := kind + ":" + value
+			if allowed[value] || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			counter, ok := counters[key]
+			if !ok {
+				counter = &atomic.Int64{}
+				counters[key] = counter
+			}
+			rules = append(rules, &ignoreRule{kind: kind, value: value, suppressed: counter})
+		}
+	}
+	add(IgnoreRulePrefix, profile.Prefixes)
+	add(IgnoreRulePrefix, options.Prefixes)
+	add(IgnoreRuleExtension, profile.Extensions)
+	add(IgnoreRuleExtension, options.Extensions)
+	return rules
+}
+
+// cleanIgnoreRules 去掉空白和重复的规则，路径前缀需要以 / 开头，扩展名不能包含 /
+func cleanIgnoreRules(prefixes []string, extensions []string) ([]string, []string, error) {
+	prefixes = cleanPatterns(prefixes)
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, nil, fmt.Errorf("忽略的路径前缀需要以 / 开头: '%s'", prefix)
+		}
+	}
+
+	extensions = cleanPatterns(extensions)
+	for _, ext := range extensions {
+		if strings.Contains(ext, "/") {
+			return nil, nil, fmt.Errorf("忽略的扩展名不能包含 /: '%s'", ext)
+		}
+	}
+
+	return prefixes, extensions, nil
+}
+
+// copyIgnoreProfile 复制规则集，避免调用方修改全局规则
+func copyIgnoreProfile(profile *IgnoreProfile) IgnoreProfile {
+	return IgnoreProfile{
+		Name:       profile.Name,
+		Prefixes:   append([]string{}, profile.Prefixes...),
+		Extensions: append([]string{}, profile.Extensions...),
+	}
+}
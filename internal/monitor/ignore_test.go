@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+func TestIgnoreStats(t *testing.T) {
+	if err := SetIgnoreOptions(IgnoreOptions{Extensions: []string{".bak"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetIgnoreOptions()
+
+	filters := currentFilters()
+	for _, path := range []string{"/tmp/a.txt", "/tmp/b.txt", "/src/a.go.bak", "/src/a.go", "/dev/null"} {
+		access := database.FileAccess{ProcessName: "vim", FilePath: path, Operation: "open"}
+		filters.evaluate(&access, "", true)
+	}
+	// 说明访问记录不计数
+	ExplainAccess(database.FileAccess{ProcessName: "vim", FilePath: "/tmp/c.txt", Operation: "open"})
+
+	want := map[string]int64{"prefix:/tmp/": 2, "prefix:/dev/": 1, "extension:.bak": 1}
+	stats := GetIgnoreStats()
+	if len(stats) == 0 {
+		t.Fatal("GetIgnoreStats returned no rules")
+	}
+	for _, rule := range stats {
+		key := rule.Type + ":" + rule.Value
+		if rule.Suppressed != want[key] {
+			t.Errorf("%s: suppressed = %d, want %d", key, rule.Suppressed, want[key])
+		}
+	}
+
+	// 重新设置规则时计数器清零
+	if err := SetIgnoreOptions(IgnoreOptions{Extensions: []string{".bak"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range GetIgnoreStats() {
+		if rule.Suppressed != 0 {
+			t.Errorf("%s:%s: suppressed = %d after SetIgnoreOptions, want 0", rule.Type, rule.Value, rule.Suppressed)
+		}
+	}
+}
+
+func TestIgnoreOptions(t *testing.T) {
+	defer ResetIgnoreOptions()
+
+	tests := []struct {
+		name    string
+		options IgnoreOptions
+		path    string
+		rule    string // 忽略该路径的规则，为空表示记录
+	}{
+		{"default profile", IgnoreOptions{}, "/tmp/a.txt", "prefix:/tmp/"},
+		{"default profile keeps other paths", IgnoreOptions{}, "/src/a.go", ""},
+		{"allow a default prefix", IgnoreOptions{Allow: []string{"/tmp/"}}, "/tmp/a.txt", ""},
+		{"allow keeps the other default rules", IgnoreOptions{Allow: []string{"/tmp/"}}, "/private/tmp/a.txt", "prefix:/private/tmp/"},
+		{"allow a default extension", IgnoreOptions{Allow: []string{".swp"}}, "/src/.a.go.swp", ""},
+		{"session extension", IgnoreOptions{Extensions: []string{".bak"}}, "/src/a.go.bak", "extension:.bak"},
+		{"no profile", IgnoreOptions{Profile: NoIgnoreProfile}, "/tmp/a.txt", ""},
+		{"no profile ignores no extensions", IgnoreOptions{Profile: NoIgnoreProfile}, "/src/.a.go.swp", ""},
+		{"no profile with session prefix", IgnoreOptions{Profile: NoIgnoreProfile, Prefixes: []string{"/data/cache/"}}, "/data/cache/a.bin", "prefix:/data/cache/"},
+	}
+
+	for _, tc := range tests {
+		if err := SetIgnoreOptions(tc.options); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := ExplainAccess(database.FileAccess{ProcessName: "vim", FilePath: tc.path, Operation: "open"})
+		if tc.rule == "" && !got.Tracked {
+			t.Errorf("%s: %s not tracked: %+v", tc.name, tc.path, got)
+		}
+		if tc.rule != "" && (got.Tracked || got.Stage != DecisionStageIgnore || got.Rule != tc.rule) {
+			t.Errorf("%s: %s got %+v, want ignored by %s", tc.name, tc.path, got, tc.rule)
+		}
+	}
+
+	// none 不使用任何规则集中的规则
+	if err := SetIgnoreOptions(IgnoreOptions{Profile: NoIgnoreProfile}); err != nil {
+		t.Fatal(err)
+	}
+	if stats := GetIgnoreStats(); len(stats) != 0 {
+		t.Errorf("profile none: rules = %+v, want none", stats)
+	}
+}
@@ -0,0 +1,378 @@
+//go:build linux
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/mine/fileWatch/internal/database"
+	"golang.org/x/sys/unix"
+)
+
+// inotify监听的事件类型
+const inotifyMask = unix.IN_OPEN | unix.IN_MODIFY | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE | unix.IN_CREATE | unix.IN_DELETE_SELF
+
+// inotifyMoveTimeout 等待与MOVED_FROM配对的MOVED_TO的时间，超时后视为移出了监控目录
+const inotifyMoveTimeout = 200 * time.Millisecond
+
+// inotifyOperations inotify事件位与操作类型的对应关系
+var inotifyOperations = []struct {
+	mask      uint32
+	operation string
+}{
+	{unix.IN_OPEN, "open"},
+	{unix.IN_MODIFY, "write"},
+	{unix.IN_CLOSE_WRITE, "close"},
+	{unix.IN_DELETE, "unlink"},
+	{unix.IN_CREATE, "create"},
+}
+
+// inotifyDirOperations 目录事件与操作类型的对应关系，列出目录产生的打开和关闭不记录
+var inotifyDirOperations = []struct {
+	mask      uint32
+	operation string
+}{
+	{unix.IN_CREATE, "mkdir"},
+	{unix.IN_DELETE, "rmdir"},
+}
+
+// pendingMove 尚未配对的MOVED_FROM事件
+type pendingMove struct {
+	path  string
+	isDir bool
+	at    time.Time
+}
+
+// InotifySource 基于Linux inotify的递归目录监控事件源
+// 不需要root权限，但inotify不提供进程信息，记录的进程名固定为UnknownProcess
+type InotifySource struct {
+	roots   []string
+	fd      int
+	file    *os.File
+	watches map[int]string
+	moves   map[uint32]pendingMove // 同一次重命名的MOVED_FROM和MOVED_TO通过cookie关联
+	events  chan database.FileAccess
+	errors  chan error
+}
+
+// NewInotifySource 创建inotify事件源，递归监控roots下的所有目录
+func NewInotifySource(roots []string) *InotifySource {
+	return &InotifySource{
+		roots:   roots,
+		watches: make(map[int]string),
+		moves:   make(map[uint32]pendingMove),
+		events:  make(chan database.FileAccess, batchSize),
+		errors:  make(chan error, 16),
+	}
+}
+
+// Roots 返回监控的根目录
+func (s *InotifySource) Roots() []string {
+	return s.roots
+}
+
+// newInotifySource 以包含目录通配符推导出的目录作为监控根目录创建inotify事件源
+func newInotifySource(config sourceConfig) (EventSource, error) {
+	return NewInotifySource(config.roots), nil
+}
+
+// Name 返回事件源名称
+func (s *InotifySource) Name() string {
+	return SourceInotify
+}
+
+// Start 初始化inotify并为根目录下的所有子目录添加监控
+func (s *InotifySource) Start() error {
+	if len(GetIncludePatterns()) == 0 {
+		return errors.New("inotify事件源需要设置包含目录通配符作为监控根目录")
+	}
+
+	if len(GetProcessPatterns()) > 0 {
+		log.Printf("警告: inotify事件源无法获取进程信息，进程通配符 %s 将被忽略", GetProcessPattern())
+	}
+
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("初始化inotify失败: %w", err)
+	}
+	s.fd = fd
+
+	for _, root := range s.roots {
+		if err := s.addTree(root); err != nil {
+			unix.Close(fd)
+			return err
+		}
+	}
+	log.Printf("inotify已监控 %d 个目录", len(s.watches))
+
+	// 非阻塞描述符交给运行时轮询，关闭文件即可让读取返回
+	s.file = os.NewFile(uintptr(fd), "inotify")
+
+	go s.readEvents()
+	return nil
+}
+
+// addTree 递归地为目录及其所有子目录添加监控
+func (s *InotifySource) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 根目录不可访问时报错，子目录不可访问时跳过
+			if path == root {
+				return fmt.Errorf("无法访问监控目录 %s: %w", root, err)
+			}
+			return nil
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		wd, err := unix.InotifyAddWatch(s.fd, path, inotifyMask)
+		if err != nil {
+			if path == root {
+				return fmt.Errorf("监控目录 %s 失败: %w", path, err)
+			}
+			s.reportError(fmt.Errorf("监控目录 %s 失败: %w", path, err))
+			return filepath.SkipDir
+		}
+		s.watches[wd] = path
+		return nil
+	})
+}
+
+// reportError 非阻塞地报告错误，错误过多时丢弃
+func (s *InotifySource) reportError(err error) {
+	select {
+	case s.errors <- err:
+	default:
+	}
+}
+
+// readEvents 循环读取并解析inotify事件
+// 重命名的两个事件可能分在两次读取中，未配对的MOVED_FROM保留到超时后再处理
+func (s *InotifySource) readEvents() {
+	defer close(s.events)
+	defer close(s.errors)
+
+	buf := make([]byte, 4096*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for {
+		s.file.SetReadDeadline(s.moveDeadline())
+		n, err := s.file.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			s.flushMoves(time.Now())
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				s.reportError(fmt.Errorf("读取inotify事件失败: %w", err))
+			}
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			if offset > n {
+				break
+			}
+
+			name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+			s.handleEvent(int(event.Wd), event.Mask, event.Cookie, name)
+		}
+
+		s.flushMoves(time.Now())
+	}
+}
+
+// moveDeadline 返回最早的未配对MOVED_FROM的超时时间，没有时不设置超时
+func (s *InotifySource) moveDeadline() time.Time {
+	var deadline time.Time
+	for _, move := range s.moves {
+		if at := move.at.Add(inotifyMoveTimeout); deadline.IsZero() || at.Before(deadline) {
+			deadline = at
+		}
+	}
+	return deadline
+}
+
+// flushMoves 处理超时仍未配对的MOVED_FROM，文件或目录被移出了监控目录
+func (s *InotifySource) flushMoves(now time.Time) {
+	for cookie, move := range s.moves {
+		if now.Sub(move.at) < inotifyMoveTimeout {
+			continue
+		}
+		delete(s.moves, cookie)
+
+		if move.isDir {
+			s.removeTree(move.path)
+		}
+		s.emitRename(move.path, "", database.CategoryDelete, move.isDir)
+	}
+}
+
+// handleEvent 处理单条inotify事件
+func (s *InotifySource) handleEvent(wd int, mask uint32, cookie uint32, name string) {
+	dir, ok := s.watches[wd]
+	if !ok {
+		return
+	}
+
+	// 目录被删除或移走后inotify会自动移除监控
+	if mask&(unix.IN_IGNORED|unix.IN_DELETE_SELF) != 0 {
+		delete(s.watches, wd)
+
+		// 上级目录不在监控范围内的根目录被删除时，没有其他事件记录删除
+		if mask&unix.IN_DELETE_SELF != 0 && s.isRoot(dir) {
+			s.emit(dir, unix.IN_DELETE, true)
+		}
+		return
+	}
+
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+	isDir := mask&unix.IN_ISDIR != 0
+
+	// 重命名需要等配对的事件到达后一起记录
+	if mask&unix.IN_MOVED_FROM != 0 {
+		s.moves[cookie] = pendingMove{path: path, isDir: isDir, at: time.Now()}
+		return
+	}
+	if mask&unix.IN_MOVED_TO != 0 {
+		from, ok := s.moves[cookie]
+		delete(s.moves, cookie)
+
+		switch {
+		case !ok:
+			// 从监控目录外移入，源路径未知，移入的目录需要添加监控
+			if isDir {
+				s.addTreeOrReport(path)
+			}
+			s.emitRename(path, "", database.CategoryCreate, isDir)
+		default:
+			// 监控目录内的重命名，已有的监控随目录移动，更新记录的路径
+			if isDir {
+				s.renameTree(from.path, path)
+			}
+			s.emitRename(from.path, path, "", isDir)
+		}
+		return
+	}
+
+	// 自动监控新建的子目录
+	if isDir && mask&unix.IN_CREATE != 0 {
+		s.addTreeOrReport(path)
+	}
+
+	s.emit(path, mask, isDir)
+}
+
+// emit 按事件位记录访问，目录只记录创建和删除，路径条件在管道中检查
+func (s *InotifySource) emit(path string, mask uint32, isDir bool) {
+	operations := inotifyOperations
+	if isDir {
+		operations = inotifyDirOperations
+	}
+
+	now := time.Now()
+	for _, op := range operations {
+		if mask&op.mask == 0 {
+			continue
+		}
+
+		access := database.FileAccess{
+			Timestamp:   now,
+			ProcessName: UnknownProcess,
+			FilePath:    path,
+			Operation:   op.operation,
+			IsDir:       isDir,
+		}
+		if isTrackedAccess(&access, SourceInotify) {
+			s.events <- access
+		}
+	}
+}
+
+// emitRename 记录一次重命名，target为空时只有一端在监控目录内
+// 移出监控目录记录为删除分类，从监控目录外移入记录为创建分类
+func (s *InotifySource) emitRename(path, target, category string, isDir bool) {
+	access := database.FileAccess{
+		Timestamp:   time.Now(),
+		ProcessName: UnknownProcess,
+		FilePath:    path,
+		TargetPath:  target,
+		Operation:   "rename",
+		Category:    category,
+		IsDir:       isDir,
+	}
+
+	if isTrackedAccess(&access, SourceInotify) {
+		s.events <- access
+	}
+}
+
+// addTreeOrReport 为新出现的目录添加监控，失败时报告错误
+func (s *InotifySource) addTreeOrReport(path string) {
+	if err := s.addTree(path); err != nil {
+		s.reportError(err)
+	}
+}
+
+// renameTree 目录在监控范围内重命名后，更新其中所有监控的路径
+func (s *InotifySource) renameTree(from, to string) {
+	for wd, path := range s.watches {
+		if isSubPath(path, from) {
+			s.watches[wd] = to + strings.TrimPrefix(path, from)
+		}
+	}
+}
+
+// removeTree 目录移出监控目录后移除其中所有的监控
+func (s *InotifySource) removeTree(dir string) {
+	for wd, path := range s.watches {
+		if isSubPath(path, dir) {
+			unix.InotifyRmWatch(s.fd, uint32(wd))
+			delete(s.watches, wd)
+		}
+	}
+}
+
+// isRoot 判断目录是否为监控的根目录
+func (s *InotifySource) isRoot(dir string) bool {
+	for _, root := range s.roots {
+		if filepath.Clean(root) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop 关闭inotify描述符，所有监控随之移除
+func (s *InotifySource) Stop() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// Events 返回访问记录通道
+func (s *InotifySource) Events() <-chan database.FileAccess {
+	return s.events
+}
+
+// Errors 返回错误通道
+func (s *InotifySource) Errors() <-chan error {
+	return s.errors
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// newInotifySource inotify仅在Linux上可用
+func newInotifySource(sourceConfig) (EventSource, error) {
+	return nil, errors.New("inotify事件源仅支持Linux")
+}
@@ -1,11 +1,10 @@
 package monitor
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
 	"log"
-	"os/exec"
-	"path/filepath"
-	"regexp"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -23,56 +22,151 @@ const (
 // 用于去重的缓存结构
 type accessKey struct {
 	process   string
+	pid       int
 	filePath  string
+	target    string
 	operation string
 }
 
 // 全局变量，用于存储当前监控的目录前缀
 var currentPathPrefix string
 
-// 全局变量，用于存储包含目录的通配符模式
-var includePattern string
-
-// 全局变量，用于存储排除目录的通配符模式
-var excludePattern string
+// StartMonitoring 开始监控文件系统访问
+// 事件源启动失败时同样等待停止信号，调用方停止监控时不会被阻塞
+func StartMonitoring(doneChan chan bool) {
+	source, err := StartEventSource()
+	if err != nil {
+		log.Println(err)
+		<-doneChan
+		return
+	}
 
-// 全局变量，用于存储包含进程的通配符模式
-var processPattern string
+	RunMonitoring(doneChan, source)
+}
 
-// 全局变量，用于存储监控目录的正则表达式
-var includeRegexPattern string
-var includeRegex *regexp.Regexp
+// StartEventSource 创建并启动当前选择的事件源，启动失败时返回错误
+// 未选择事件源且fanotify因权限不足无法启动时，改用inotify事件源
+func StartEventSource() (EventSource, error) {
+	source, err := startEventSource(sourceName, currentSourceConfig())
+	if err != nil {
+		return nil, err
+	}
 
-// 全局变量，用于存储排除目录的正则表达式
-var excludeRegexPattern string
-var excludeRegex *regexp.Regexp
+	if source.Name() != GetEventSource() {
+		sourceName = source.Name()
+	}
+	setSourceRoots(source)
+	return source, nil
+}
 
-// StartMonitoring 开始监控文件系统访问
-func StartMonitoring(doneChan chan bool) {
-	log.Println("开始监控文件系统访问...")
+// startEventSource 按指定的设置创建并启动事件源
+// 未指定事件源且没有权限使用fanotify时改用inotify，调用方根据返回的事件源名称判断
+func startEventSource(name string, config sourceConfig) (EventSource, error) {
+	source, err := newEventSource(name, config)
+	if err != nil {
+		return nil, fmt.Errorf("创建事件源失败: %w", err)
+	}
 
-	// 执行fs_usage命令，增加-w参数以显示完整路径
-	cmd := exec.Command("sudo", "fs_usage", "-w", "-f", "filesystem")
-	stdout, err := cmd.StdoutPipe()
+	err = source.Start()
+	if err != nil && name == "" && source.Name() == SourceFanotify && errors.Is(err, os.ErrPermission) {
+		log.Printf("启动fanotify事件源失败: %v，改用inotify事件源", err)
+		fallback, fallbackErr := newEventSource(SourceInotify, config)
+		if fallbackErr == nil {
+			fallbackErr = fallback.Start()
+		}
+		if fallbackErr != nil {
+			return nil, fmt.Errorf("启动事件源 %s 失败: %w；改用inotify也失败: %v", source.Name(), err, fallbackErr)
+		}
+		return fallback, nil
+	}
 	if err != nil {
-		log.Printf("创建管道失败: %v", err)
-		return
+		return nil, fmt.Errorf("启动事件源 %s 失败: %w", source.Name(), err)
 	}
+	return source, nil
+}
 
-	if err := cmd.Start(); err != nil {
-		log.Printf("启动fs_usage命令失败: %v", err)
+// setSourceRoots 记录已启动的事件源监控的目录，运行中替换包含目录通配符时据此检查
+func setSourceRoots(source EventSource) {
+	sourceRoots = nil
+	if rooted, ok := source.(rootedSource); ok {
+		sourceRoots = rooted.Roots()
+	}
+}
+
+// StartMonitoringWithSource 使用指定的事件源开始监控文件系统访问
+// 事件源启动失败时同样等待停止信号，调用方停止监控时不会被阻塞
+func StartMonitoringWithSource(doneChan chan bool, source EventSource) {
+	if err := source.Start(); err != nil {
+		log.Printf("启动事件源 %s 失败: %v", source.Name(), err)
+		<-doneChan
 		return
 	}
 
-	// 创建批处理缓冲区
-	var (
-		accessBuffer = make([]database.FileAccess, 0, batchSize)
-		bufferMutex  sync.Mutex
-		stopChan     = make(chan bool)
-		// 用于去重的缓存
-		recentAccesses = make(map[accessKey]time.Time)
-		cacheMutex     sync.Mutex
-	)
+	RunMonitoring(doneChan, source)
+}
+
+// RunMonitoring 读取已启动的事件源产生的访问记录，直到收到停止信号
+func RunMonitoring(doneChan chan bool, source EventSource) {
+	log.Printf("开始监控文件系统访问，事件源: %s", source.Name())
+
+	// 每次监控会话重新记录观察到的进程
+	database.ClearProcesses()
+
+	pipeline := newAccessPipeline(source.Name(), !isOfflineSource(source))
+	pipeline.start()
+
+	// 记录事件源运行期间产生的错误
+	go func() {
+		for err := range source.Errors() {
+			log.Printf("事件源 %s 出错: %v", source.Name(), err)
+		}
+	}()
+
+	// 读取事件源产生的访问记录
+	go func() {
+		for access := range source.Events() {
+			pipeline.add(access)
+		}
+	}()
+
+	// 等待停止信号
+	<-doneChan
+	pipeline.stop() // 通知刷新goroutine退出
+
+	if err := source.Stop(); err != nil {
+		log.Printf("停止事件源 %s 失败: %v", source.Name(), err)
+	}
+	log.Println("已停止监控文件系统访问")
+}
+
+// accessPipeline 对访问记录进行去重、批处理并写入存储
+type accessPipeline struct {
+	accessBuffer []database.FileAccess
+	bufferMutex  sync.Mutex
+	stopChan     chan bool
+	// 用于去重的缓存
+	recentAccesses map[accessKey]time.Time
+	cacheMutex     sync.Mutex
+	// 维护进程表
+	processes *processTracker
+	// 产生访问记录的事件源名称
+	source string
+}
+
+// newAccessPipeline 创建新的访问记录处理管道，live表示事件来自正在运行的进程
+func newAccessPipeline(source string, live bool) *accessPipeline {
+	return &accessPipeline{
+		source:         source,
+		accessBuffer:   make([]database.FileAccess, 0, batchSize),
+		stopChan:       make(chan bool),
+		recentAccesses: make(map[accessKey]time.Time),
+		processes:      newProcessTracker(live),
+	}
+}
+
+// start 启动定期清理缓存和刷新缓冲区的goroutine
+func (p *accessPipeline) start() {
+	p.processes.start(p.stopChan)
 
 	// 定期清理去重缓存
 	go func() {
@@ -82,8 +176,8 @@ func StartMonitoring(doneChan chan bool) {
 		for {
 			select {
 			case <-ticker.C:
-				cleanupAccessCache(&recentAccesses, &cacheMutex)
-			case <-stopChan:
+				cleanupAccessCache(&p.recentAccesses, &p.cacheMutex)
+			case <-p.stopChan:
 				return
 			}
 		}
@@ -97,75 +191,88 @@ func StartMonitoring(doneChan chan bool) {
 		for {
 			select {
 			case <-ticker.C:
-				flushAccessBuffer(&accessBuffer, &bufferMutex)
-			case <-stopChan:
-				// 确保退出前刷新所有数据
-				flushAccessBuffer(&accessBuffer, &bufferMutex)
+				flushAccessBuffer(&p.accessBuffer, &p.bufferMutex)
+			case <-p.stopChan:
 				return
 			}
 		}
 	}()
+}
 
-	// 使用扫描器读取命令输出
-	scanner := bufio.NewScanner(stdout)
-	go func() {
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			// 解析fs_usage输出行
-			if access := parseFsUsageLine(line); access != nil {
-				// 检查去重缓存，避免短时间内记录同一文件的重复操作
-				key := accessKey{
-					process:   access.ProcessName,
-					filePath:  access.FilePath,
-					operation: access.Operation,
-				}
-
-				cacheMutex.Lock()
-				lastTime, exists := recentAccesses[key]
-				now := time.Now()
-
-				// 如果相同操作在抖动时间内出现过，则跳过
-				if exists && now.Sub(lastTime) < debounceTime {
-					cacheMutex.Unlock()
-					continue
-				}
-
-				// 更新缓存
-				recentAccesses[key] = now
-				cacheMutex.Unlock()
-
-				// 添加到缓冲区
-				bufferMutex.Lock()
-				accessBuffer = append(accessBuffer, *access)
-
-				// 如果达到批处理大小，则刷新到数据库
-				if len(accessBuffer) >= batchSize {
-					// 复制当前缓冲区并清空，然后解锁，以便继续收集数据
-					currentBatch := make([]database.FileAccess, len(accessBuffer))
-					copy(currentBatch, accessBuffer)
-					accessBuffer = accessBuffer[:0]
-					bufferMutex.Unlock()
-
-					// 批量存储到数据库
-					if err := database.AddFileAccessBatch(currentBatch); err != nil {
-						log.Printf("批量存储文件访问记录失败: %v", err)
-					}
-				} else {
-					bufferMutex.Unlock()
-				}
-			}
-		}
-	}()
+// stop 停止后台goroutine，并刷新剩余的数据
+func (p *accessPipeline) stop() {
+	close(p.stopChan)
 
-	// 等待停止信号
-	<-doneChan
-	close(stopChan) // 通知刷新goroutine退出
+	// 确保退出前刷新所有数据
+	flushAccessBuffer(&p.accessBuffer, &p.bufferMutex)
+}
 
-	if err := cmd.Process.Kill(); err != nil {
-		log.Printf("停止fs_usage命令失败: %v", err)
+// add 添加一条访问记录，短时间内的重复操作会被忽略，返回记录是否被保留
+func (p *accessPipeline) add(access database.FileAccess) bool {
+	// 事件源未给出分类时根据操作类型确定
+	if access.Category == "" {
+		access.Category = OperationCategory(access.Operation)
 	}
-	log.Println("已停止监控文件系统访问")
+
+	// 所有记录都用于更新进程表，fork、exit等生命周期事件不作为访问记录保存
+	p.processes.observe(&access)
+	if isLifecycleOperation(access.Operation) {
+		return false
+	}
+
+	if decision := currentFilters().evaluate(&access, p.source, true); !decision.Tracked {
+		return false
+	}
+
+	// 检查去重缓存，避免短时间内记录同一文件的重复操作
+	key := accessKey{
+		process:   access.ProcessName,
+		pid:       access.PID,
+		filePath:  access.FilePath,
+		target:    access.TargetPath,
+		operation: access.Operation,
+	}
+
+	p.cacheMutex.Lock()
+	lastTime, exists := p.recentAccesses[key]
+
+	// 以事件发生的时间去重，回放时不受回放速度影响
+	now := access.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// 如果相同操作在抖动时间内出现过，则跳过
+	if exists && now.Sub(lastTime) >= 0 && now.Sub(lastTime) < debounceTime {
+		p.cacheMutex.Unlock()
+		return false
+	}
+
+	// 更新缓存
+	p.recentAccesses[key] = now
+	p.cacheMutex.Unlock()
+
+	// 添加到缓冲区
+	p.bufferMutex.Lock()
+	p.accessBuffer = append(p.accessBuffer, access)
+
+	// 如果达到批处理大小，则刷新到数据库
+	if len(p.accessBuffer) >= batchSize {
+		// 复制当前缓冲区并清空，然后解锁，以便继续收集数据
+		currentBatch := make([]database.FileAccess, len(p.accessBuffer))
+		copy(currentBatch, p.accessBuffer)
+		p.accessBuffer = p.accessBuffer[:0]
+		p.bufferMutex.Unlock()
+
+		// 批量存储到数据库
+		if err := database.AddFileAccessBatch(currentBatch); err != nil {
+			log.Printf("批量存储文件访问记录失败: %v", err)
+		}
+	} else {
+		p.bufferMutex.Unlock()
+	}
+
+	return true
 }
 
 // cleanupAccessCache 清理过期的缓存条目
@@ -202,104 +309,6 @@ func flushAccessBuffer(buffer *[]database.FileAccess, mutex *sync.Mutex) {
 	}
 }
 
-// parseFsUsageLine 解析fs_usage命令的单行输出
-func parseFsUsageLine(line string) *database.FileAccess {
-	// 跳过空行、标题行和其他非数据行
-	if !strings.Contains(line, "/") {
-		return nil
-	}
-
-	// 将行分割成字段
-	fields := strings.Fields(line)
-	if len(fields) < 4 {
-		return nil // 至少需要时间戳、操作类型和进程信息
-	}
-
-	// 提取时间戳和操作类型
-	operation := fields[1]
-
-	// 只记录读写文件的操作
-	if !isReadWriteOperation(operation) {
-		return nil
-	}
-
-	// 提取进程信息（通常是最后一个字段）
-	processInfo := fields[len(fields)-1]
-	processName := parseProcessInfo(processInfo)
-
-	// 根据进程名过滤
-	if !shouldTrackProcess(processName) {
-		return nil
-	}
-
-	// 提取文件路径
-	filePath := extractFilePathSimple(line, fields)
-	if filePath == "" {
-		return nil
-	}
-
-	// 检查是否需要跟踪这个文件
-	if !shouldTrackFile(filePath) {
-		return nil
-	}
-
-	// 创建文件访问记录
-	return &database.FileAccess{
-		Timestamp:   time.Now(),
-		ProcessName: processName,
-		FilePath:    filePath,
-		Operation:   operation,
-	}
-}
-
-// parseProcessInfo 从进程信息字符串中提取进程名
-func parseProcessInfo(info string) string {
-	processName := info
-
-	// 尝试解析进程名和PID (格式通常是 processName.PID)
-	lastDot := strings.LastIndex(info, ".")
-	if lastDot > 0 && lastDot < len(info)-1 {
-		processName = info[:lastDot]
-		// 不再提取PID
-	}
-
-	return processName
-}
-
-// extractFilePathSimple 使用简单的字符串方法从输出行中提取文件路径
-func extractFilePathSimple(line string, fields []string) string {
-	// 寻找以/开头的字段，这很可能是文件路径
-	for _, field := range fields {
-		if strings.HasPrefix(field, "/") {
-			return field
-		}
-	}
-
-	// 检查是否有截断的路径（如 ystem/Volumes/）
-	for i, field := range fields {
-		if i > 0 && (strings.Contains(field, "/Volumes/") ||
-			strings.Contains(field, "Library/") ||
-			strings.Contains(field, "/Users/")) {
-
-			// 可能是截断的路径
-			if strings.HasPrefix(field, "ystem/") {
-				return "/S" + field
-			} else if strings.HasPrefix(field, "olumes/") {
-				return "/V" + field
-			} else if strings.HasPrefix(field, "ibrary/") {
-				return "/L" + field
-			} else if strings.HasPrefix(field, "sers/") {
-				return "/U" + field
-			} else {
-				// 其他情况，如果看起来像是路径的一部分，加上前缀
-				return "/" + field
-			}
-		}
-	}
-
-	return ""
-}
-
 // isReadWriteOperation 判断操作是否为读写文件相关操作
 func isReadWriteOperation(operation string) bool {
 	// 定义读写相关的操作类型
@@ -329,162 +338,13 @@ func isReadWriteOperation(operation string) bool {
 	return readWriteOperations[operation]
 }
 
-// shouldTrackFile 判断是否应该记录该文件的访问
-func shouldTrackFile(path string) bool {
-	// 如果设置了包含通配符，则只记录匹配的路径
-	if includePattern != "" && !matchWildcard(path, includePattern) {
-		return false
-	}
-
-	// 如果设置了排除通配符，则排除匹配的路径
-	if excludePattern != "" && matchWildcard(path, excludePattern) {
-		return false
-	}
-
-	// 忽略系统目录和临时文件
-	ignoredPrefixes := []string{
-		"/dev/",
-		"/usr/share/",
-		"/private/var/folders/",
-		"/System/Library/",
-		"/Library/Caches/",
-		"/Library/Logs/",
-		"/var/log/",
-		"/var/db/",
-		"/private/tmp/",
-		"/tmp/",
-		"/Library/Apple/",
-		"/Library/PrivilegedHelperTools/",
-		"/Applications/Xcode.app/Contents/",
-	}
-
-	for _, prefix := range ignoredPrefixes {
-		if strings.HasPrefix(path, prefix) {
-			return false
-		}
-	}
-
-	// 忽略某些临时文件扩展名
-	ignoredExtensions := []string{
-		".tmp",
-		".temp",
-		".cache",
-		".swap",
-		".swp",
-		".DS_Store",
-		".localized",
-		".git",
-	}
-
-	for _, ext := range ignoredExtensions {
-		if strings.HasSuffix(path, ext) {
-			return false
-		}
-	}
-
-	// 默认保留
-	return true
-}
-
-// shouldTrackProcess 判断是否应该记录该进程的访问
-func shouldTrackProcess(processName string) bool {
-	// 如果没有设置进程通配符，则记录所有进程
-	if processPattern == "" {
-		return true
-	}
-
-	// 否则只记录匹配通配符的进程
-	return matchWildcard(processName, processPattern)
-}
-
-// matchWildcard 判断路径是否匹配通配符模式
-// 支持 * 匹配任意字符序列, ? 匹配单个字符
-func matchWildcard(path, pattern string) bool {
-	// 使用Go标准库的filepath.Match函数进行通配符匹配
-	matched, err := filepath.Match(pattern, path)
-	if err != nil {
-		// 如果模式无效，记录错误并返回false
-		log.Printf("通配符模式 '%s' 无效: %v", pattern, err)
+// isTrackedPath 判断路径是否满足通配符、忽略规则集和gitignore文件，不计入忽略规则的计数器
+// isDir 表示路径是否为目录，由事件源给出，无法区分时为false
+func (f *filterSet) isTrackedPath(path string, isDir bool) bool {
+	if !f.matchPath(path) || f.matchIgnore(path) != nil {
 		return false
 	}
-
-	// 如果直接匹配成功，返回true
-	if matched {
-		return true
-	}
-
-	// filepath.Match只支持匹配单个路径段，但我们需要匹配整个路径
-	// 如果pattern包含**/，表示匹配任意层级目录
-	if strings.Contains(pattern, "**/") {
-		parts := strings.Split(pattern, "**/")
-		if len(parts) == 2 {
-			prefix := parts[0]
-			suffix := parts[1]
-
-			// 检查路径是否以prefix开头
-			if prefix == "" || strings.HasPrefix(path, prefix) {
-				// 遍历所有可能的目录层级
-				restPath := path
-				if prefix != "" {
-					restPath = path[len(prefix):]
-				}
-
-				// 处理路径中的所有子目录
-				dirs := strings.Split(restPath, "/")
-				for i := 0; i < len(dirs); i++ {
-					subPath := strings.Join(dirs[i:], "/")
-					matched, err := filepath.Match(suffix, subPath)
-					if err == nil && matched {
-						return true
-					}
-				}
-			}
-		}
-	}
-
-	// 如果模式以*开头或结尾，我们需要进行部分匹配
-	if strings.HasPrefix(pattern, "*") && strings.HasSuffix(path, pattern[1:]) {
-		return true
-	}
-
-	if strings.HasSuffix(pattern, "*") && strings.HasPrefix(path, pattern[:len(pattern)-1]) {
-		return true
-	}
-
-	// 多个通配符分段匹配
-	if strings.Count(pattern, "*") > 1 {
-		segments := strings.Split(pattern, "*")
-		if segments[0] != "" && !strings.HasPrefix(path, segments[0]) {
-			return false
-		}
-
-		if segments[len(segments)-1] != "" && !strings.HasSuffix(path, segments[len(segments)-1]) {
-			return false
-		}
-
-		// 检查中间部分是否按顺序出现
-		current := path
-		for i := 0; i < len(segments); i++ {
-			if segments[i] == "" {
-				continue
-			}
-
-			index := strings.Index(current, segments[i])
-			if index == -1 {
-				return false
-			}
-			current = current[index+len(segments[i]):]
-		}
-
-		return true
-	}
-
-	return false
-}
-
-// GetFSUsageCommand 返回适合用户执行的fs_usage命令
-func GetFSUsageCommand() string {
-	return "sudo fs_usage -w -f filesystem"
+	return !f.matchGitignore(path, isDir)
 }
 
 // StartMonitoringWithPrefix 开始监控文件系统访问，支持指定目录前缀
@@ -496,13 +356,15 @@ func StartMonitoringWithPrefix(doneChan chan bool, pathPattern string) {
 	if pathPattern != "" {
 		// 自动将前缀路径转为通配符模式
 		wildcardPattern := pathPattern + "*"
-		SetIncludePattern(wildcardPattern)
+		if err := SetIncludePattern(wildcardPattern); err != nil {
+			log.Printf("设置目录前缀失败: %v", err)
+		}
 	} else {
 		// 如果没有提供路径，清除通配符
 		ResetIncludePattern()
 	}
 
-	log.Printf("开始监控文件系统访问，目录匹配模式: %s", includePattern)
+	log.Printf("开始监控文件系统访问，目录匹配模式: %s", GetIncludePattern())
 
 	// 调用原有监控函数
 	StartMonitoring(doneChan)
@@ -512,68 +374,110 @@ func StartMonitoringWithPrefix(doneChan chan bool, pathPattern string) {
 func StartMonitoringWithWildcards(doneChan chan bool, includeWildcard string, excludeWildcard string, processWildcard string) {
 	// 设置包含通配符
 	if includeWildcard != "" {
-		SetIncludePattern(includeWildcard)
+		if err := SetIncludePattern(includeWildcard); err != nil {
+			log.Printf("设置通配符失败: %v", err)
+		}
 	} else {
 		ResetIncludePattern()
 	}
 
 	// 设置排除通配符
 	if excludeWildcard != "" {
-		SetExcludePattern(excludeWildcard)
+		if err := SetExcludePattern(excludeWildcard); err != nil {
+			log.Printf("设置通配符失败: %v", err)
+		}
 	} else {
 		ResetExcludePattern()
 	}
 
 	// 设置进程通配符
 	if processWildcard != "" {
-		SetProcessPattern(processWildcard)
+		if err := SetProcessPattern(processWildcard); err != nil {
+			log.Printf("设置通配符失败: %v", err)
+		}
 	} else {
 		ResetProcessPattern()
 	}
 
 	log.Printf("开始监控文件系统访问，包含路径通配符: %s, 排除路径通配符: %s, 进程通配符: %s",
-		includePattern, excludePattern, processPattern)
+		GetIncludePattern(), GetExcludePattern(), GetProcessPattern())
 
 	// 调用原有监控函数
 	StartMonitoring(doneChan)
 }
 
-// SetIncludePattern 设置包含目录的通配符
-func SetIncludePattern(pattern string) {
-	if pattern == "" {
+// patternSeparator 多个通配符显示为一个字符串时使用的分隔符
+const patternSeparator = "; "
+
+// SetIncludePattern 设置包含目录的通配符，可以设置多个，路径匹配任意一个即记录
+func SetIncludePattern(patterns ...string) error {
+	patterns = cleanPatterns(patterns)
+	if len(patterns) == 0 {
 		ResetIncludePattern()
-		return
+		return nil
+	}
+
+	matchers, err := compilePatterns(patterns, true)
+	if err != nil {
+		return err
 	}
 
-	includePattern = pattern
-	log.Printf("已设置包含目录通配符: %s", pattern)
+	updateFilters(func(f *filterSet) {
+		f.include = matchers
+		f.includePatterns = patterns
+	})
+	log.Printf("已设置包含目录通配符: %s", GetIncludePattern())
+	return nil
+}
+
+// GetIncludePatterns 获取当前的包含目录通配符列表
+func GetIncludePatterns() []string {
+	return append([]string{}, currentFilters().includePatterns...)
 }
 
-// GetIncludePattern 获取当前的包含目录通配符
+// GetIncludePattern 获取当前的包含目录通配符，多个时以分号分隔
 func GetIncludePattern() string {
-	return includePattern
+	return strings.Join(currentFilters().includePatterns, patternSeparator)
 }
 
 // ResetIncludePattern 重置包含目录通配符
 func ResetIncludePattern() {
-	includePattern = ""
+	updateFilters(func(f *filterSet) {
+		f.include = nil
+		f.includePatterns = nil
+	})
 	log.Println("已重置包含目录通配符")
 }
 
-// SetExcludePattern 设置排除目录的通配符
-func SetExcludePattern(pattern string) {
-	if pattern == "" {
+// SetExcludePattern 设置排除目录的通配符，可以设置多个，路径匹配任意一个即排除
+func SetExcludePattern(patterns ...string) error {
+	patterns = cleanPatterns(patterns)
+	if len(patterns) == 0 {
 		ResetExcludePattern()
-		return
+		return nil
 	}
 
-	excludePattern = pattern
-	log.Printf("已设置排除目录通配符: %s", pattern)
+	matchers, err := compilePatterns(patterns, true)
+	if err != nil {
+		return err
+	}
+
+	updateFilters(func(f *filterSet) {
+		f.exclude = matchers
+		f.excludePatterns = patterns
+	})
+	log.Printf("已设置排除目录通配符: %s", GetExcludePattern())
+	return nil
+}
+
+// GetExcludePatterns 获取当前的排除目录通配符列表
+func GetExcludePatterns() []string {
+	return append([]string{}, currentFilters().excludePatterns...)
 }
 
-// GetExcludePattern 获取当前的排除目录通配符
+// GetExcludePattern 获取当前的排除目录通配符，多个时以分号分隔
 func GetExcludePattern() string {
-	return excludePattern
+	return strings.Join(currentFilters().excludePatterns, patternSeparator)
 }
 
 // ResetPathPrefix 重置监控目录前缀
@@ -586,7 +490,10 @@ func ResetPathPrefix() {
 
 // ResetExcludePattern 重置排除目录通配符
 func ResetExcludePattern() {
-	excludePattern = ""
+	updateFilters(func(f *filterSet) {
+		f.exclude = nil
+		f.excludePatterns = nil
+	})
 	log.Println("已重置排除目录通配符")
 }
 
@@ -595,16 +502,18 @@ func GetCurrentPathPrefix() string {
 	return currentPathPrefix
 }
 
-// 以下函数为了保持向后兼容性，但内部实现已改为通配符
+// 以下函数为了保持向后兼容性，正则表达式以 regex: 前缀的模式保存
 
 // SetIncludeRegex 设置包含目录的正则表达式（兼容旧API）
 func SetIncludeRegex(pattern string) error {
-	log.Println("警告: SetIncludeRegex 已弃用，请使用 SetIncludePattern")
-	SetIncludePattern(pattern)
-	return nil
+	if pattern == "" {
+		ResetIncludePattern()
+		return nil
+	}
+	return SetIncludePattern(patternPrefixRegex + pattern)
 }
 
-// GetIncludeRegex 获取当前的包含目录正则表达式（兼容旧API）
+// GetIncludeRegex 获取当前的包含目录模式（兼容旧API）
 func GetIncludeRegex() string {
 	return GetIncludePattern()
 }
@@ -616,12 +525,14 @@ func ResetIncludeRegex() {
 
 // SetExcludeRegex 设置排除目录的正则表达式（兼容旧API）
 func SetExcludeRegex(pattern string) error {
-	log.Println("警告: SetExcludeRegex 已弃用，请使用 SetExcludePattern")
-	SetExcludePattern(pattern)
-	return nil
+	if pattern == "" {
+		ResetExcludePattern()
+		return nil
+	}
+	return SetExcludePattern(patternPrefixRegex + pattern)
 }
 
-// GetExcludeRegex 获取当前的排除目录正则表达式（兼容旧API）
+// GetExcludeRegex 获取当前的排除目录模式（兼容旧API）
 func GetExcludeRegex() string {
 	return GetExcludePattern()
 }
@@ -631,30 +542,70 @@ func ResetExcludeRegex() {
 	ResetExcludePattern()
 }
 
-// StartMonitoringWithRegex 开始监控文件系统访问（兼容旧API）
-func StartMonitoringWithRegex(doneChan chan bool, includePattern string, excludePattern string) {
-	log.Println("警告: StartMonitoringWithRegex 已弃用，请使用 StartMonitoringWithWildcards")
-	StartMonitoringWithWildcards(doneChan, includePattern, excludePattern, "")
+// StartMonitoringWithRegex 开始监控文件系统访问，使用正则表达式过滤目录（兼容旧API）
+func StartMonitoringWithRegex(doneChan chan bool, includeRegex string, excludeRegex string) {
+	log.Println("警告: StartMonitoringWithRegex 已弃用，请使用 StartMonitoringWithWildcards 和 regex: 前缀")
+	if err := SetIncludeRegex(includeRegex); err != nil {
+		log.Printf("设置包含目录正则表达式失败: %v", err)
+	}
+	if err := SetExcludeRegex(excludeRegex); err != nil {
+		log.Printf("设置排除目录正则表达式失败: %v", err)
+	}
+	ResetProcessPattern()
+	StartMonitoring(doneChan)
 }
 
-// SetProcessPattern 设置包含进程的通配符
-func SetProcessPattern(pattern string) {
-	if pattern == "" {
+// SetProcessPattern 设置包含进程的通配符，可以设置多个，进程名匹配任意一个即记录
+func SetProcessPattern(patterns ...string) error {
+	patterns = cleanPatterns(patterns)
+	if len(patterns) == 0 {
 		ResetProcessPattern()
-		return
+		return nil
 	}
 
-	processPattern = pattern
-	log.Printf("已设置包含进程通配符: %s", pattern)
+	matchers, err := compilePatterns(patterns, false)
+	if err != nil {
+		return err
+	}
+
+	updateFilters(func(f *filterSet) {
+		f.process = matchers
+		f.processPatterns = patterns
+	})
+	log.Printf("已设置包含进程通配符: %s", GetProcessPattern())
+	return nil
 }
 
-// GetProcessPattern 获取当前的包含进程通配符
+// GetProcessPatterns 获取当前的包含进程通配符列表
+func GetProcessPatterns() []string {
+	return append([]string{}, currentFilters().processPatterns...)
+}
+
+// GetProcessPattern 获取当前的包含进程通配符，多个时以分号分隔
 func GetProcessPattern() string {
-	return processPattern
+	return strings.Join(currentFilters().processPatterns, patternSeparator)
 }
 
 // ResetProcessPattern 重置包含进程通配符
 func ResetProcessPattern() {
-	processPattern = ""
+	updateFilters(func(f *filterSet) {
+		f.process = nil
+		f.processPatterns = nil
+	})
 	log.Println("已重置包含进程通配符")
 }
+
+// cleanPatterns 去掉空白和重复的通配符，保持原有顺序
+func cleanPatterns(patterns []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+		result = append(result, pattern)
+	}
+	return result
+}
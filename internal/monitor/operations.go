@@ -0,0 +1,289 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// 全局变量，用于存储当前会话记录的操作类型，与操作分类都为空时只记录默认的读写操作
+var trackedOperations map[string]bool
+
+// 全局变量，用于存储当前会话记录的操作分类
+var trackedCategories map[string]bool
+
+// 全局变量，用于存储是否开启删除审计模式
+var deletionAudit bool
+
+// operationCategories 操作类型与分类的对应关系，未列出的操作按名称推断
+var operationCategories = map[string]string{
+	"open":               database.CategoryRead,
+	"open_nocancel":      database.CategoryRead,
+	"open_extended":      database.CategoryRead,
+	"openat":             database.CategoryRead,
+	"openat_nocancel":    database.CategoryRead,
+	"open_dprotected_np": database.CategoryRead,
+	"guarded_open_np":    database.CategoryRead,
+	"close":              database.CategoryRead,
+	"close_nocancel":     database.CategoryRead,
+	"guarded_close_np":   database.CategoryRead,
+	"mmap":               database.CategoryRead,
+	"sendfile":           database.CategoryRead,
+
+	"truncate":     database.CategoryWrite,
+	"ftruncate":    database.CategoryWrite,
+	"fsync":        database.CategoryWrite,
+	"fdatasync":    database.CategoryWrite,
+	"rename":       database.CategoryWrite,
+	"renameat":     database.CategoryWrite,
+	"renamex_np":   database.CategoryWrite,
+	"renameatx_np": database.CategoryWrite,
+	"exchangedata": database.CategoryWrite,
+
+	"create":         database.CategoryCreate,
+	"creat":          database.CategoryCreate,
+	"mkdir":          database.CategoryCreate,
+	"mkdirat":        database.CategoryCreate,
+	"mkdir_extended": database.CategoryCreate,
+	"mkfifo":         database.CategoryCreate,
+	"mknod":          database.CategoryCreate,
+	"link":           database.CategoryCreate,
+	"linkat":         database.CategoryCreate,
+	"symlink":        database.CategoryCreate,
+	"symlinkat":      database.CategoryCreate,
+	"clonefile":      database.CategoryCreate,
+	"clonefileat":    database.CategoryCreate,
+	"fclonefileat":   database.CategoryCreate,
+
+	"unlink":   database.CategoryDelete,
+	"unlinkat": database.CategoryDelete,
+	"rmdir":    database.CategoryDelete,
+	"delete":   database.CategoryDelete,
+
+	"access":          database.CategoryMetadata,
+	"faccessat":       database.CategoryMetadata,
+	"chdir":           database.CategoryMetadata,
+	"fchdir":          database.CategoryMetadata,
+	"chmod":           database.CategoryMetadata,
+	"fchmod":          database.CategoryMetadata,
+	"fchmodat":        database.CategoryMetadata,
+	"chmod_extended":  database.CategoryMetadata,
+	"chown":           database.CategoryMetadata,
+	"fchown":          database.CategoryMetadata,
+	"lchown":          database.CategoryMetadata,
+	"fchownat":        database.CategoryMetadata,
+	"utimes":          database.CategoryMetadata,
+	"futimes":         database.CategoryMetadata,
+	"utimensat":       database.CategoryMetadata,
+	"readlink":        database.CategoryMetadata,
+	"readlinkat":      database.CategoryMetadata,
+	"pathconf":        database.CategoryMetadata,
+	"fpathconf":       database.CategoryMetadata,
+	"getdirentries":   database.CategoryMetadata,
+	"getdirentries64": database.CategoryMetadata,
+	"getdents64":      database.CategoryMetadata,
+	"fcntl":           database.CategoryMetadata,
+	"flock":           database.CategoryMetadata,
+
+	"execve":      database.CategoryExec,
+	"execveat":    database.CategoryExec,
+	"exec":        database.CategoryExec,
+	"posix_spawn": database.CategoryExec,
+	"open_exec":   database.CategoryExec,
+}
+
+// OperationCategory 返回操作类型所属的分类
+func OperationCategory(operation string) string {
+	if category, ok := operationCategories[operation]; ok {
+		return category
+	}
+
+	// 按名称推断，如 read_nocancel、pwrite、getattrlist、lstat64 等
+	switch {
+	case isDiskIOOperation(operation):
+		if strings.HasPrefix(operation, "Wr") || strings.HasPrefix(operation, "PgOut") {
+			return database.CategoryWrite
+		}
+		return database.CategoryRead
+	case strings.HasPrefix(operation, "CACHE_HIT"):
+		return database.CategoryRead
+	case strings.Contains(operation, "write"):
+		return database.CategoryWrite
+	case strings.Contains(operation, "read"):
+		return database.CategoryRead
+	case strings.Contains(operation, "stat"), strings.Contains(operation, "attr"):
+		return database.CategoryMetadata
+	case strings.HasPrefix(operation, "exec"):
+		return database.CategoryExec
+	}
+	return database.CategoryOther
+}
+
+// isTrackedOperation 判断当前会话是否记录该操作
+func isTrackedOperation(operation string) bool {
+	// 删除审计模式下需要删除和重命名操作，重命名是否算作删除在管道中判断
+	if deletionAudit && (OperationCategory(operation) == database.CategoryDelete || isRenameOperation(operation)) {
+		return true
+	}
+	return isConfiguredOperation(operation)
+}
+
+// isTrackedAccess 判断事件源产生的记录是否在当前会话的记录范围内，事件源解析时、管道和 ExplainAccess 共用
+// 未自定义记录的操作时，inotify记录所有监听的事件，fs_usage选择filesystem以外的模式即表示需要该模式的全部事件
+func isTrackedAccess(access *database.FileAccess, source string) bool {
+	if isTrackedOperation(access.Operation) {
+		return true
+	}
+	if hasTrackedOperations() || deletionAudit {
+		return false
+	}
+	return source == SourceInotify || (access.Mode != "" && access.Mode != FSUsageModeFilesystem)
+}
+
+// isConfiguredOperation 判断操作是否在会话配置的操作中
+// 未自定义操作时记录默认的读写操作，删除审计模式下则只记录删除
+func isConfiguredOperation(operation string) bool {
+	if !hasTrackedOperations() {
+		return !deletionAudit && isReadWriteOperation(operation)
+	}
+	return trackedOperations[operation] || trackedCategories[OperationCategory(operation)]
+}
+
+// isDirOperation 判断是否为只作用于目录的操作
+func isDirOperation(operation string) bool {
+	switch operation {
+	case "mkdir", "mkdirat", "rmdir", "chdir", "fchdir", "getdirentries", "getdirentries64", "getattrlistbulk":
+		return true
+	}
+	return false
+}
+
+// accessIsDir 根据事件源给出的标记或操作类型判断访问的路径是否为目录，不查询文件系统
+func accessIsDir(access *database.FileAccess) bool {
+	return access.IsDir || isDirOperation(access.Operation)
+}
+
+// isRenameOperation 判断是否为重命名操作
+func isRenameOperation(operation string) bool {
+	switch operation {
+	case "rename", "renameat", "renameat2", "renamex_np", "renameatx_np":
+		return true
+	}
+	return false
+}
+
+// auditDeletion 删除审计模式下判断是否保留访问记录
+// 目标路径不在监控范围内的重命名（移出监控目录）视为删除，其他操作只保留会话配置的操作
+func (f *filterSet) auditDeletion(access *database.FileAccess) bool {
+	if access.Category == database.CategoryDelete {
+		return true
+	}
+
+	// 目标路径未知时无法判断是否移出了监控范围，由事件源确定时直接标记为删除分类
+	if isRenameOperation(access.Operation) && access.TargetPath != "" &&
+		f.isTrackedPath(access.FilePath, access.IsDir) && !f.isTrackedPath(access.TargetPath, access.IsDir) {
+		access.Category = database.CategoryDelete
+		return true
+	}
+
+	return isConfiguredOperation(access.Operation)
+}
+
+// SetDeletionAudit 设置是否开启删除审计模式
+// 开启后记录unlink、rmdir以及移出监控范围的重命名，用于追查文件被谁删除
+func SetDeletionAudit(enabled bool) {
+	deletionAudit = enabled
+	if enabled {
+		log.Println("已开启删除审计模式")
+	}
+}
+
+// GetDeletionAudit 获取是否开启删除审计模式
+func GetDeletionAudit() bool {
+	return deletionAudit
+}
+
+// ResetDeletionAudit 关闭删除审计模式
+func ResetDeletionAudit() {
+	deletionAudit = false
+}
+
+// hasTrackedOperations 判断当前会话是否自定义了记录的操作
+func hasTrackedOperations() bool {
+	return len(trackedOperations) > 0 || len(trackedCategories) > 0
+}
+
+// SetTrackedOperations 设置当前会话记录的操作类型和操作分类，两者为或的关系
+// 都为空时恢复为默认的读写操作
+func SetTrackedOperations(operations []string, categories []string) error {
+	operationSet, categorySet, err := compileTrackedOperations(operations, categories)
+	if err != nil {
+		return err
+	}
+	if operationSet == nil {
+		ResetTrackedOperations()
+		return nil
+	}
+
+	trackedOperations = operationSet
+	trackedCategories = categorySet
+	log.Printf("已设置记录的操作: %v, 操作分类: %v", operations, categories)
+	return nil
+}
+
+// compileTrackedOperations 将记录的操作类型和操作分类整理为集合，都为空时返回nil表示使用默认的读写操作
+func compileTrackedOperations(operations []string, categories []string) (map[string]bool, map[string]bool, error) {
+	categorySet := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		if !isValidCategory(category) {
+			return nil, nil, fmt.Errorf("未知的操作分类: %s，可选值: %s", category, strings.Join(database.Categories, ", "))
+		}
+		categorySet[category] = true
+	}
+
+	operationSet := make(map[string]bool, len(operations))
+	for _, operation := range operations {
+		if operation = strings.TrimSpace(operation); operation != "" {
+			operationSet[operation] = true
+		}
+	}
+
+	if len(operationSet) == 0 && len(categorySet) == 0 {
+		return nil, nil, nil
+	}
+	return operationSet, categorySet, nil
+}
+
+// GetTrackedOperations 获取当前会话记录的操作类型和操作分类
+func GetTrackedOperations() ([]string, []string) {
+	return sortedKeys(trackedOperations), sortedKeys(trackedCategories)
+}
+
+// ResetTrackedOperations 恢复为只记录默认的读写操作
+func ResetTrackedOperations() {
+	trackedOperations = nil
+	trackedCategories = nil
+}
+
+// isValidCategory 判断是否为有效的操作分类
+func isValidCategory(category string) bool {
+	for _, c := range database.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys 返回集合中排序后的元素
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+//go:build darwin
+
+package monitor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// lookupProcessName 当前平台不支持通过/proc查询进程名
+func lookupProcessName(pid int) string {
+	return ""
+}
+
+// lookupThreadGroup 当前平台不支持通过/proc查询线程所属进程
+func lookupThreadGroup(tid int) int {
+	return 0
+}
+
+// lookupProcessCwd 当前平台不支持通过/proc查询工作目录
+func lookupProcessCwd(pid int) string {
+	return ""
+}
+
+// lookupProcessMetadata 通过sysctl查询进程的父进程、程序路径、命令行和用户，进程不存在时返回false
+// 不启动ps等子进程，子进程本身也会被fs_usage记录，每个新进程再触发一次查询
+func lookupProcessMetadata(pid int) (processMetadata, bool) {
+	proc, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil || int(proc.Proc.P_pid) != pid {
+		return processMetadata{}, false
+	}
+
+	metadata := processMetadata{
+		PPID: int(proc.Eproc.Ppid),
+		User: lookupUserName(strconv.FormatUint(uint64(proc.Eproc.Pcred.P_ruid), 10)),
+	}
+
+	// 读取其他用户进程的参数需要权限，失败时留空
+	if args, err := unix.SysctlRaw("kern.procargs2", pid); err == nil {
+		metadata.ExecPath, metadata.Cmdline = parseProcArgs(args)
+	}
+
+	return metadata, true
+}
+
+// parseProcArgs 解析kern.procargs2的结果，返回程序路径和命令行
+// 格式为参数个数（int32）、程序路径、若干个\0填充、各个参数，参数之后是环境变量
+func parseProcArgs(data []byte) (string, string) {
+	if len(data) < 4 {
+		return "", ""
+	}
+	argc := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+
+	execPath, data, _ := bytes.Cut(data, []byte{0})
+	data = bytes.TrimLeft(data, "\x00")
+
+	args := make([]string, 0, argc)
+	for len(args) < argc && len(data) > 0 {
+		var arg []byte
+		arg, data, _ = bytes.Cut(data, []byte{0})
+		args = append(args, string(arg))
+	}
+
+	return string(execPath), strings.TrimSpace(strings.Join(args, " "))
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lookupProcessName 从/proc读取进程名，进程不存在时返回空字符串
+func lookupProcessName(pid int) string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// lookupThreadGroup 从/proc读取线程所属进程的PID，无法获取时返回0
+func lookupThreadGroup(tid int) int {
+	return readProcStatusInt(tid, "Tgid:")
+}
+
+// lookupProcessCwd 从/proc读取进程的工作目录，无法获取时返回空字符串
+func lookupProcessCwd(pid int) string {
+	cwd, _ := os.Readlink("/proc/" + strconv.Itoa(pid) + "/cwd")
+	return cwd
+}
+
+// lookupProcessMetadata 从/proc读取进程的父进程、程序路径、命令行和用户，进程不存在时返回false
+func lookupProcessMetadata(pid int) (processMetadata, bool) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	status, err := os.ReadFile(dir + "/status")
+	if err != nil {
+		return processMetadata{}, false
+	}
+
+	var metadata processMetadata
+	for _, line := range strings.Split(string(status), "\n") {
+		if value, ok := strings.CutPrefix(line, "PPid:"); ok {
+			metadata.PPID, _ = strconv.Atoi(strings.TrimSpace(value))
+		} else if value, ok := strings.CutPrefix(line, "Uid:"); ok {
+			// 依次为实际、有效、保存和文件系统UID，取实际UID
+			if fields := strings.Fields(value); len(fields) > 0 {
+				metadata.User = lookupUserName(fields[0])
+			}
+		}
+	}
+
+	// 读取其他用户进程的exe需要权限，失败时留空
+	metadata.ExecPath, _ = os.Readlink(dir + "/exe")
+
+	// 命令行参数以\0分隔
+	if cmdline, err := os.ReadFile(dir + "/cmdline"); err == nil {
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		metadata.Cmdline = strings.TrimSpace(strings.Join(args, " "))
+	}
+
+	return metadata, true
+}
+
+// readProcStatusInt 读取/proc/<pid>/status中的整数字段
+func readProcStatusInt(pid int, key string) int {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, key); ok {
+			n, _ := strconv.Atoi(strings.TrimSpace(value))
+			return n
+		}
+	}
+	return 0
+}
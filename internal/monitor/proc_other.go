@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package monitor
+
+// lookupProcessName 当前平台不支持通过/proc查询进程名
+func lookupProcessName(pid int) string {
+	return ""
+}
+
+// lookupThreadGroup 当前平台不支持通过/proc查询线程所属进程
+func lookupThreadGroup(tid int) int {
+	return 0
+}
+
+// lookupProcessCwd 当前平台不支持通过/proc查询工作目录
+func lookupProcessCwd(pid int) string {
+	return ""
+}
+
+// lookupProcessMetadata 当前平台不支持查询进程信息
+func lookupProcessMetadata(pid int) (processMetadata, bool) {
+	return processMetadata{}, false
+}
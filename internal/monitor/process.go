@@ -0,0 +1,241 @@
+package monitor
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// 进程生命周期事件的操作类型，只用于更新进程表，不作为文件访问记录保存
+const (
+	OperationFork = "fork"
+	OperationExit = "exit"
+)
+
+// offlineSource 读取已保存输出的事件源实现该接口
+// 记录中的进程可能已经退出或PID已被复用，不能查询系统中的进程信息
+type offlineSource interface {
+	Offline() bool
+}
+
+// processMetadata 从系统中查询到的进程信息
+type processMetadata struct {
+	PPID     int
+	ExecPath string
+	Cmdline  string
+	User     string
+}
+
+// processLookupQueueSize 等待查询进程信息的PID数量上限
+// 队列已满时放弃本次查询，在该进程的下一条记录时重试
+const processLookupQueueSize = 256
+
+// processTracker 位于解析和写入存储之间，根据记录维护进程表并补充进程信息
+// 进程表以PID为键，同时作为进程信息的缓存，每个PID只查询一次
+// 查询在后台goroutine中进行，不阻塞事件的处理
+type processTracker struct {
+	live     bool
+	lookedUp map[int]uint64 // 已经提交查询的PID及查询序号
+	seq      uint64
+	lookups  chan processLookup
+	mu       sync.Mutex
+}
+
+// processLookup 一次进程信息查询，seq用于丢弃进程exec或PID复用之前提交的查询结果
+type processLookup struct {
+	pid int
+	seq uint64
+}
+
+// newProcessTracker 创建进程表维护器，live表示可以查询系统中的进程信息
+func newProcessTracker(live bool) *processTracker {
+	return &processTracker{
+		live:     live,
+		lookedUp: make(map[int]uint64),
+		lookups:  make(chan processLookup, processLookupQueueSize),
+	}
+}
+
+// start 启动查询进程信息的goroutine，stop关闭时退出
+func (t *processTracker) start(stop <-chan bool) {
+	if !t.live {
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case lookup := <-t.lookups:
+				if metadata, ok := lookupProcessMetadata(lookup.pid); ok {
+					t.enrich(lookup, metadata)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// enrich 使用查询结果补充进程表，查询期间进程exec或PID被复用时丢弃结果
+func (t *processTracker) enrich(lookup processLookup, metadata processMetadata) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.lookedUp[lookup.pid] != lookup.seq {
+		return
+	}
+
+	info, exists := database.GetProcess(lookup.pid)
+	if !exists {
+		return
+	}
+	old := info
+	enrichProcess(&info, metadata)
+	if info != old {
+		database.SaveProcess(info)
+	}
+}
+
+// requestLookup 提交进程信息查询，队列已满时不记录，在该进程的下一条记录时重试
+func (t *processTracker) requestLookup(pid int) {
+	t.seq++
+	select {
+	case t.lookups <- processLookup{pid: pid, seq: t.seq}:
+		t.lookedUp[pid] = t.seq
+	default:
+	}
+}
+
+// isLifecycleOperation 判断是否为只用于更新进程表的生命周期事件
+func isLifecycleOperation(operation string) bool {
+	return operation == OperationFork || operation == OperationExit
+}
+
+// isSelfExecOperation 判断exec操作的路径是否为当前进程新执行的程序
+// posix_spawn等操作记录在父进程上，路径是子进程的程序
+// fanotify的open_exec对程序和动态链接器都会产生，程序路径从系统中查询
+func isSelfExecOperation(operation string) bool {
+	switch operation {
+	case "execve", "execveat", "exec":
+		return true
+	}
+	return false
+}
+
+// observe 根据记录更新进程表，并在记录上补充父进程PID
+func (t *processTracker) observe(access *database.FileAccess) {
+	if access.PID <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	old, exists := database.GetProcess(access.PID)
+	info := old
+
+	// fork或exec时已退出的进程说明PID被复用
+	starting := access.Operation == OperationFork || isSelfExecOperation(access.Operation)
+	if !exists || (starting && old.ExitedAt != nil) {
+		info = database.ProcessInfo{
+			PID:       access.PID,
+			Name:      access.ProcessName,
+			StartedAt: access.Timestamp,
+		}
+		delete(t.lookedUp, access.PID)
+	}
+
+	switch {
+	case access.Operation == OperationFork:
+		info.StartedAt = access.Timestamp
+		if access.PPID > 0 {
+			info.PPID = access.PPID
+		}
+
+	case access.Operation == OperationExit:
+		exitedAt := access.Timestamp
+		info.ExitedAt = &exitedAt
+
+	case isSelfExecOperation(access.Operation) && access.Errno == 0:
+		info.ExecPath = access.FilePath
+		info.Name = filepath.Base(access.FilePath)
+		if info.StartedAt.IsZero() || !exists {
+			info.StartedAt = access.Timestamp
+		}
+
+		// exec之后命令行发生变化，在下一条记录时重新查询
+		info.Cmdline = ""
+		delete(t.lookedUp, access.PID)
+	}
+
+	if info.PPID == 0 && access.PPID > 0 {
+		info.PPID = access.PPID
+	}
+
+	// 尽早查询进程信息，短暂运行的进程在读取到后续记录时可能已经退出
+	// 进程退出后无法查询；fanotify的open_exec发生在exec完成之前，查询到的仍是原来的程序
+	// 本进程的信息不需要查询
+	if t.live && access.Operation != OperationExit && access.Operation != "open_exec" &&
+		t.lookedUp[access.PID] == 0 && access.PID != os.Getpid() {
+		t.requestLookup(access.PID)
+	}
+
+	access.PPID = info.PPID
+	if !exists || info != old {
+		database.SaveProcess(info)
+	}
+}
+
+// enrichProcess 使用查询到的进程信息补充进程表，事件源给出的信息优先
+func enrichProcess(info *database.ProcessInfo, metadata processMetadata) {
+	if info.PPID == 0 {
+		info.PPID = metadata.PPID
+	}
+	if info.ExecPath == "" {
+		info.ExecPath = metadata.ExecPath
+	}
+	if metadata.Cmdline != "" {
+		info.Cmdline = metadata.Cmdline
+	}
+	if metadata.User != "" {
+		info.User = metadata.User
+	}
+
+	// fs_usage输出的进程名会被截断，使用程序路径中的完整名称
+	if base := filepath.Base(metadata.ExecPath); metadata.ExecPath != "" && len(base) > len(info.Name) && strings.HasPrefix(base, info.Name) {
+		info.Name = base
+	}
+}
+
+// 用户名缓存，每个UID只查询一次
+var (
+	userNames   = make(map[string]string)
+	userNamesMu sync.Mutex
+)
+
+// lookupUserName 返回UID对应的用户名，查询失败时返回UID
+func lookupUserName(uid string) string {
+	userNamesMu.Lock()
+	defer userNamesMu.Unlock()
+
+	if name, ok := userNames[uid]; ok {
+		return name
+	}
+
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	userNames[uid] = name
+	return name
+}
+
+// isOfflineSource 判断事件源是否读取已保存的输出
+func isOfflineSource(source EventSource) bool {
+	offline, ok := source.(offlineSource)
+	return ok && offline.Offline()
+}
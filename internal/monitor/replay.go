@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// SourceReplay 回放事件源名称
+const SourceReplay = "replay"
+
+// ErrCaptureStartUnknown 捕获文件没有记录开始时间，也没有指定捕获日期
+var ErrCaptureStartUnknown = errors.New("捕获文件没有记录开始时间，请指定捕获开始的日期")
+
+// ReplaySource 回放已保存的fs_usage输出的事件源
+// 捕获文件可以通过 sudo fs_usage -w -f filesystem > capture.txt 获得
+type ReplaySource struct {
+	reader   io.Reader
+	base     time.Time
+	realtime bool
+	stopChan chan struct{}
+	stopOnce sync.Once
+	events   chan database.FileAccess
+	errors   chan error
+}
+
+// NewReplaySource 创建回放事件源，realtime为true时按捕获中的原始时间间隔回放，否则尽快回放
+// 捕获中只有时刻，优先使用捕获文件第一行记录的开始时间，没有记录时以base为捕获开始的日期
+func NewReplaySource(reader io.Reader, base time.Time, realtime bool) *ReplaySource {
+	return &ReplaySource{
+		reader:   reader,
+		base:     base,
+		realtime: realtime,
+		stopChan: make(chan struct{}),
+		events:   make(chan database.FileAccess, batchSize),
+		errors:   make(chan error, 1),
+	}
+}
+
+// Name 返回事件源名称
+func (s *ReplaySource) Name() string {
+	return SourceReplay
+}
+
+// Start 开始回放，无法确定捕获开始的时间时返回 ErrCaptureStartUnknown
+func (s *ReplaySource) Start() error {
+	// 开始时间行较短，预读而不消耗，回放时与后续轮转文件的开始时间行一起处理
+	reader := bufio.NewReader(s.reader)
+	head, _ := reader.Peek(len(captureHeaderPrefix) + captureHeaderMaxTime)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+
+	base, ok := parseCaptureHeader(string(head))
+	if !ok {
+		if s.base.IsZero() {
+			return ErrCaptureStartUnknown
+		}
+		base = s.base
+	}
+
+	go func() {
+		defer close(s.events)
+		defer close(s.errors)
+
+		parser := newFSUsageParser(base)
+		var last time.Time
+
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			// 多个轮转的捕获文件拼接在一起时，按每个文件的开始时间重新确定日期
+			if start, ok := parseCaptureHeader(line); ok {
+				parser.clock = newClockTracker(start)
+				continue
+			}
+
+			access := parser.parseLine(line)
+			if access == nil {
+				continue
+			}
+
+			// 按照相邻两条记录的时间差等待，还原原始的时间间隔
+			if s.realtime {
+				if !last.IsZero() && access.Timestamp.After(last) && !s.wait(access.Timestamp.Sub(last)) {
+					return
+				}
+				last = access.Timestamp
+			}
+
+			select {
+			case s.events <- *access:
+			case <-s.stopChan:
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			s.errors <- fmt.Errorf("读取捕获文件失败: %w", err)
+		}
+	}()
+
+	return nil
+}
+
+// wait 等待指定时长，回放被停止时返回false
+func (s *ReplaySource) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopChan:
+		return false
+	}
+}
+
+// Stop 停止回放
+func (s *ReplaySource) Stop() error {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+	return nil
+}
+
+// Offline 回放的记录来自已保存的捕获文件
+func (s *ReplaySource) Offline() bool {
+	return true
+}
+
+// Events 返回访问记录通道
+func (s *ReplaySource) Events() <-chan database.FileAccess {
+	return s.events
+}
+
+// Errors 返回错误通道
+func (s *ReplaySource) Errors() <-chan error {
+	return s.errors
+}
+
+// ReplayCapture 回放fs_usage捕获内容，经过与实时监控相同的去重和批处理后写入存储
+// 回放结束后返回写入存储的记录数
+func ReplayCapture(reader io.Reader, base time.Time, realtime bool) (int, error) {
+	source := NewReplaySource(reader, base, realtime)
+	if err := source.Start(); err != nil {
+		return 0, err
+	}
+
+	pipeline := newAccessPipeline(source.Name(), false)
+	pipeline.start()
+
+	count := 0
+	for access := range source.Events() {
+		if pipeline.add(access) {
+			count++
+		}
+	}
+	pipeline.stop()
+
+	// 事件通道关闭时错误通道已经关闭
+	for err := range source.Errors() {
+		return count, err
+	}
+	return count, nil
+}
+
+// ReplayCaptureFile 回放fs_usage捕获文件，文件没有记录开始时间时以base为捕获开始的日期
+func ReplayCaptureFile(path string, base time.Time, realtime bool) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("打开捕获文件失败: %w", err)
+	}
+	defer file.Close()
+
+	log.Printf("开始回放捕获文件: %s", path)
+	count, err := ReplayCapture(file, base, realtime)
+	log.Printf("捕获文件回放结束，共写入 %d 条记录", count)
+	return count, err
+}
+
+// CaptureStartTime 读取捕获文件第一行记录的开始时间，没有记录时返回false
+func CaptureStartTime(path string) (time.Time, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+
+	line, _ := bufio.NewReader(file).ReadString('\n')
+	return parseCaptureHeader(line)
+}
@@ -0,0 +1,253 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// 规则列表中的匹配对象前缀，未指定时匹配路径
+const (
+	rulePrefixPath    = "path:"
+	rulePrefixProcess = "process:"
+)
+
+// filterRule 规则列表中的一条规则
+//
+// 规则的格式与rsync的过滤规则类似，每条规则以 + 或 - 开头，如排除build目录但保留其中的报告：
+//
+//	"rules": ["+ path:**/build/reports/**", "- path:**/build/**", "+ process:node*"]
+//
+// 规则按顺序匹配，第一条匹配的规则决定记录或忽略，没有规则匹配时记录
+// path: 规则匹配文件路径（重命名等操作的任意一端），process: 规则匹配进程名，
+// 模式的语法与通配符相同，可以使用 regex: 前缀
+type filterRule struct {
+	text    string
+	include bool
+	process bool
+	matcher patternMatcher
+}
+
+// SetFilterRules 设置规则列表，规则按顺序匹配，第一条匹配的规则生效
+// 任意一条规则无效时返回错误且不修改当前设置
+func SetFilterRules(rules ...string) error {
+	compiled, err := compileFilterRules(rules)
+	if err != nil {
+		return err
+	}
+	if len(compiled) == 0 {
+		ResetFilterRules()
+		return nil
+	}
+
+	updateFilters(func(f *filterSet) { f.rules = compiled })
+	log.Printf("已设置 %d 条过滤规则", len(compiled))
+	return nil
+}
+
+// compileFilterRules 编译规则列表，忽略空白和重复的规则，任意一条无效时返回错误
+func compileFilterRules(rules []string) ([]filterRule, error) {
+	rules = cleanPatterns(rules)
+	compiled := make([]filterRule, 0, len(rules))
+	for i, text := range rules {
+		rule, err := compileFilterRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条规则 '%s' 无效: %w", i+1, text, err)
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// GetFilterRules 获取当前的规则列表
+func GetFilterRules() []string {
+	rules := currentFilters().rules
+	texts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		texts = append(texts, rule.text)
+	}
+	return texts
+}
+
+// ResetFilterRules 重置规则列表
+func ResetFilterRules() {
+	updateFilters(func(f *filterSet) { f.rules = nil })
+	log.Println("已重置过滤规则")
+}
+
+// compileFilterRule 解析并编译一条规则
+func compileFilterRule(text string) (filterRule, error) {
+	rule := filterRule{text: text}
+
+	switch text[0] {
+	case '+':
+		rule.include = true
+	case '-':
+	default:
+		return rule, fmt.Errorf("规则需要以 + 或 - 开头")
+	}
+
+	pattern := strings.TrimSpace(text[1:])
+	if p, ok := strings.CutPrefix(pattern, rulePrefixProcess); ok {
+		rule.process = true
+		pattern = p
+	} else {
+		pattern = strings.TrimPrefix(pattern, rulePrefixPath)
+	}
+
+	matcher, err := compilePattern(pattern, !rule.process)
+	if err != nil {
+		return rule, err
+	}
+	rule.matcher = matcher
+	return rule, nil
+}
+
+// matchRules 按顺序匹配规则列表，返回第一条匹配的规则的序号，没有规则匹配时返回-1
+// 路径规则匹配源路径或目标路径中的任意一个
+func (f *filterSet) matchRules(access *database.FileAccess) int {
+	for i, rule := range f.rules {
+		if rule.process {
+			if rule.matcher.match(access.ProcessName) {
+				return i
+			}
+			continue
+		}
+
+		if rule.matcher.match(access.FilePath) || (access.TargetPath != "" && rule.matcher.match(access.TargetPath)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FilterDecision 说明一条访问记录是否会被记录，以及由哪个环节的哪条规则决定
+type FilterDecision struct {
+	Tracked bool   `json:"tracked"`
+	Stage   string `json:"stage"`           // 做出决定的环节，见 DecisionStage 常量
+	Rule    string `json:"rule,omitempty"`  // 做出决定的规则或模式
+	Index   int    `json:"index,omitempty"` // 规则列表中的序号，从1开始
+	Path    string `json:"path,omitempty"`  // 规则匹配的路径
+}
+
+// 做出决定的环节，按监控时的检查顺序排列
+const (
+	DecisionStageOperation = "operation" // 操作类型不在记录范围内
+	DecisionStageProcess   = "process"   // 进程通配符
+	DecisionStageInclude   = "include"   // 包含目录通配符
+	DecisionStageExclude   = "exclude"   // 排除目录通配符
+	DecisionStageIgnore    = "ignore"    // 忽略规则集
+	DecisionStageGitignore = "gitignore" // gitignore文件
+	DecisionStageRules     = "rules"     // 规则列表
+	DecisionStageScope     = "scope"     // 进程的路径范围
+	DecisionStageFilter    = "filter"    // 过滤表达式
+	DecisionStageDefault   = "default"   // 没有规则排除，默认记录
+)
+
+// ExplainAccess 按监控时的检查顺序说明一条访问记录在当前事件源下是否会被记录
+// 与记录管道使用同一个函数，不经过去重，也不更新忽略规则的计数器
+func ExplainAccess(access database.FileAccess) FilterDecision {
+	if access.Category == "" && access.Operation != "" {
+		access.Category = OperationCategory(access.Operation)
+	}
+	return currentFilters().evaluate(&access, GetEventSource(), false)
+}
+
+// evaluate 按顺序判断一条访问记录是否会被记录，记录管道和 ExplainAccess 共用
+// 依次检查操作类型、进程通配符、路径条件、删除审计、规则列表、进程的路径范围和过滤表达式，
+// 所有检查使用调用方取得的同一份过滤条件；未给出操作类型时不检查操作类型
+// count 为true时被忽略规则排除的记录计入该规则的计数器，删除审计模式下移出监控范围的重命名会被标记为删除分类
+func (f *filterSet) evaluate(access *database.FileAccess, source string, count bool) FilterDecision {
+	if access.Operation != "" && (isLifecycleOperation(access.Operation) || !isTrackedAccess(access, source)) {
+		return FilterDecision{Stage: DecisionStageOperation, Rule: access.Operation}
+	}
+
+	if !f.matchProcess(access.ProcessName) {
+		return FilterDecision{Stage: DecisionStageProcess, Rule: joinMatchers(f.process)}
+	}
+
+	// 重命名等操作只要有一端位于监控范围内就记录，以便跟踪先写临时文件再重命名覆盖的保存方式
+	// 都不满足时说明源路径的原因
+	isDir := accessIsDir(access)
+	if decision, tracked := f.explainPath(access.FilePath, isDir); !tracked {
+		if access.TargetPath != "" {
+			_, tracked = f.explainPath(access.TargetPath, isDir)
+		}
+		if !tracked {
+			if rule := f.matchIgnore(access.FilePath); count && rule != nil {
+				rule.suppressed.Add(1)
+			}
+			return decision
+		}
+	}
+
+	if deletionAudit && !f.auditDeletion(access) {
+		return FilterDecision{Stage: DecisionStageOperation, Rule: access.Operation}
+	}
+
+	decision := FilterDecision{Tracked: true, Stage: DecisionStageDefault}
+	if i := f.matchRules(access); i >= 0 {
+		rule := f.rules[i]
+		decision = FilterDecision{Tracked: rule.include, Stage: DecisionStageRules, Rule: rule.text, Index: i + 1}
+		if !rule.process {
+			decision.Path = access.FilePath
+			if !rule.matcher.match(access.FilePath) {
+				decision.Path = access.TargetPath
+			}
+		}
+		if !rule.include {
+			return decision
+		}
+	}
+
+	if i, inScope := f.matchScope(access); i >= 0 {
+		if !inScope || decision.Stage == DecisionStageDefault {
+			decision = FilterDecision{Tracked: inScope, Stage: DecisionStageScope, Rule: f.scopes[i].text, Index: i + 1}
+		}
+		if !inScope {
+			return decision
+		}
+	}
+
+	if !f.matchCapture(*access) {
+		return FilterDecision{Stage: DecisionStageFilter, Rule: f.expression}
+	}
+
+	return decision
+}
+
+// explainPath 说明路径是否满足通配符、忽略规则集和gitignore文件，不满足时返回决定的环节和规则
+func (f *filterSet) explainPath(path string, isDir bool) (FilterDecision, bool) {
+	if len(f.include) > 0 && !matchAnyPattern(path, f.include) {
+		return FilterDecision{Stage: DecisionStageInclude, Rule: joinMatchers(f.include), Path: path}, false
+	}
+
+	for _, matcher := range f.exclude {
+		if matcher.match(path) {
+			return FilterDecision{Stage: DecisionStageExclude, Rule: matcher.String(), Path: path}, false
+		}
+	}
+
+	if rule := f.matchIgnore(path); rule != nil {
+		return FilterDecision{Stage: DecisionStageIgnore, Rule: rule.kind + ":" + rule.value, Path: path}, false
+	}
+
+	if f.gitignore != nil {
+		if rule := f.gitignore.matchRule(path, isDir); rule != nil {
+			return FilterDecision{Stage: DecisionStageGitignore, Rule: rule.source, Path: path}, false
+		}
+	}
+
+	return FilterDecision{}, true
+}
+
+// joinMatchers 将一组模式显示为一个字符串
+func joinMatchers(matchers []patternMatcher) string {
+	patterns := make([]string, 0, len(matchers))
+	for _, matcher := range matchers {
+		patterns = append(patterns, matcher.String())
+	}
+	return strings.Join(patterns, patternSeparator)
+}
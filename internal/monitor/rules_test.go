@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+func TestFilterRulesFirstMatch(t *testing.T) {
+	if err := SetFilterRules("+ **/build/reports/**", "- **/build/**", "+ process:node*", "- /srv/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetFilterRules()
+
+	tests := []struct {
+		process string
+		path    string
+		target  string
+		tracked bool
+		index   int
+	}{
+		{"make", "/src/app/build/reports/test.xml", "", true, 1},
+		{"make", "/src/app/build/out.o", "", false, 2},
+		{"node", "/src/app/build/out.js", "", false, 2},
+		{"node", "/srv/www/index.js", "", true, 3},
+		{"nginx", "/srv/www/index.html", "", false, 4},
+		{"mv", "/src/app/build/out.o", "/src/app/build/reports/out.o", true, 1},
+		{"vim", "/src/app/main.go", "", true, 0},
+	}
+
+	filters := currentFilters()
+	for _, tc := range tests {
+		access := database.FileAccess{ProcessName: tc.process, FilePath: tc.path, TargetPath: tc.target, Operation: "open"}
+		decision := filters.evaluate(&access, "", false)
+		if decision.Tracked != tc.tracked || decision.Index != tc.index {
+			t.Errorf("%s %s: tracked = %v, index = %d, want %v, %d", tc.process, tc.path, decision.Tracked, decision.Index, tc.tracked, tc.index)
+		}
+	}
+}
+
+func TestExplainAccess(t *testing.T) {
+	if err := SetIncludePattern("/src/**", "/srv/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetIncludePattern()
+	if err := SetExcludePattern("**/node_modules/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetExcludePattern()
+	if err := SetProcessPattern("vim", "node", "make"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetProcessPattern()
+	if err := SetFilterRules("+ **/build/reports/**", "- **/build/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetFilterRules()
+
+	tests := []struct {
+		name   string
+		source string
+		access database.FileAccess
+		want   FilterDecision
+	}{
+		{
+			name:   "untracked operation",
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/src/a.go", Operation: "chmod"},
+			want:   FilterDecision{Stage: DecisionStageOperation, Rule: "chmod"},
+		},
+		{
+			name:   "inotify records all watched events by default",
+			source: SourceInotify,
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/src/a.go", Operation: "unlink"},
+			want:   FilterDecision{Tracked: true, Stage: DecisionStageDefault},
+		},
+		{
+			name:   "other sources record read and write by default",
+			source: SourceStrace,
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/src/a.go", Operation: "unlink"},
+			want:   FilterDecision{Stage: DecisionStageOperation, Rule: "unlink"},
+		},
+		{
+			name:   "fs_usage modes other than filesystem",
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/src/a.go", Operation: "RdData[A]", Mode: FSUsageModeDiskIO},
+			want:   FilterDecision{Tracked: true, Stage: DecisionStageDefault},
+		},
+		{
+			name:   "process pattern",
+			access: database.FileAccess{ProcessName: "bash", FilePath: "/src/a.go", Operation: "open"},
+			want:   FilterDecision{Stage: DecisionStageProcess, Rule: "vim; node; make"},
+		},
+		{
+			name:   "include pattern",
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/etc/hosts", Operation: "open"},
+			want:   FilterDecision{Stage: DecisionStageInclude, Rule: "/src/**; /srv/**", Path: "/etc/hosts"},
+		},
+		{
+			name:   "exclude pattern",
+			access: database.FileAccess{ProcessName: "node", FilePath: "/src/node_modules/x/index.js", Operation: "open"},
+			want:   FilterDecision{Stage: DecisionStageExclude, Rule: "**/node_modules/**", Path: "/src/node_modules/x/index.js"},
+		},
+		{
+			name:   "ignore rule",
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/src/.a.go.swp", Operation: "write"},
+			want:   FilterDecision{Stage: DecisionStageIgnore, Rule: "extension:.swp", Path: "/src/.a.go.swp"},
+		},
+		{
+			name:   "rename into the monitored paths",
+			access: database.FileAccess{ProcessName: "vim", FilePath: "/etc/a.go", TargetPath: "/src/a.go", Operation: "rename"},
+			want:   FilterDecision{Tracked: true, Stage: DecisionStageDefault},
+		},
+		{
+			name:   "first matching rule keeps reports",
+			access: database.FileAccess{ProcessName: "make", FilePath: "/src/build/reports/a.xml", Operation: "write"},
+			want:   FilterDecision{Tracked: true, Stage: DecisionStageRules, Rule: "+ **/build/reports/**", Index: 1, Path: "/src/build/reports/a.xml"},
+		},
+		{
+			name:   "later rule drops the rest of build",
+			access: database.FileAccess{ProcessName: "make", FilePath: "/src/build/a.o", Operation: "write"},
+			want:   FilterDecision{Stage: DecisionStageRules, Rule: "- **/build/**", Index: 2, Path: "/src/build/a.o"},
+		},
+	}
+
+	defer func(name string) { sourceName = name }(sourceName)
+	for _, tc := range tests {
+		sourceName = tc.source
+		if got := ExplainAccess(tc.access); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
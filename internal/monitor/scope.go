@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// ProcessScope 进程的路径范围，进程名匹配时只记录该进程访问的指定路径
+//
+// 范围按顺序匹配，使用第一个进程名匹配的范围，没有范围匹配时记录，例如
+// ssh-agent和gpg记录所有路径，其他进程只记录 ~/.ssh 下的访问：
+//
+//	[{"process": "{ssh-agent,gpg*}"}, {"process": "*", "include": ["~/.ssh/**"]}]
+type ProcessScope struct {
+	Process string   `json:"process"`           // 进程通配符，可以使用 regex: 前缀
+	Include []string `json:"include,omitempty"` // 记录的路径通配符，为空时记录所有路径
+	Exclude []string `json:"exclude,omitempty"` // 忽略的路径通配符
+}
+
+// processScope 编译后的进程路径范围
+type processScope struct {
+	text    string // 进程通配符，用于说明访问记录由哪个范围决定
+	process patternMatcher
+	include []patternMatcher
+	exclude []patternMatcher
+}
+
+var (
+	// 全局变量，用于存储进程的路径范围，停止监控后保留，供下次会话使用
+	processScopes []ProcessScope
+
+	scopeMu sync.Mutex
+)
+
+// SetProcessScopes 替换所有的进程路径范围，立即生效，任意一个无效时返回错误且不修改当前设置
+func SetProcessScopes(scopes []ProcessScope) error {
+	scopeMu.Lock()
+	defer scopeMu.Unlock()
+
+	return applyProcessScopes(scopes)
+}
+
+// AddProcessScope 在末尾添加一个进程路径范围
+func AddProcessScope(scope ProcessScope) error {
+	scopeMu.Lock()
+	defer scopeMu.Unlock()
+
+	return applyProcessScopes(append(append([]ProcessScope{}, processScopes...), scope))
+}
+
+// DeleteProcessScope 删除指定序号（从1开始）的进程路径范围
+func DeleteProcessScope(index int) error {
+	scopeMu.Lock()
+	defer scopeMu.Unlock()
+
+	if index < 1 || index > len(processScopes) {
+		return fmt.Errorf("进程路径范围 %d 不存在", index)
+	}
+
+	scopes := append([]ProcessScope{}, processScopes[:index-1]...)
+	return applyProcessScopes(append(scopes, processScopes[index:]...))
+}
+
+// GetProcessScopes 获取所有的进程路径范围
+func GetProcessScopes() []ProcessScope {
+	scopeMu.Lock()
+	defer scopeMu.Unlock()
+
+	return append([]ProcessScope{}, processScopes...)
+}
+
+// ResetProcessScopes 删除所有的进程路径范围
+func ResetProcessScopes() {
+	scopeMu.Lock()
+	defer scopeMu.Unlock()
+
+	processScopes = nil
+	updateFilters(func(f *filterSet) { f.scopes = nil })
+}
+
+// applyProcessScopes 编译并替换进程路径范围，调用方需要持有scopeMu
+func applyProcessScopes(scopes []ProcessScope) error {
+	cleaned, compiled, err := compileProcessScopes(scopes)
+	if err != nil {
+		return err
+	}
+
+	processScopes = cleaned
+	updateFilters(func(f *filterSet) { f.scopes = compiled })
+	log.Printf("已设置 %d 个进程路径范围", len(cleaned))
+	return nil
+}
+
+// compileProcessScopes 整理并编译一组进程路径范围，任意一个无效时返回错误
+func compileProcessScopes(scopes []ProcessScope) ([]ProcessScope, []processScope, error) {
+	cleaned := make([]ProcessScope, 0, len(scopes))
+	compiled := make([]processScope, 0, len(scopes))
+	for i, scope := range scopes {
+		scope.Process = strings.TrimSpace(scope.Process)
+		scope.Include = cleanPatterns(scope.Include)
+		scope.Exclude = cleanPatterns(scope.Exclude)
+
+		compiledScope, err := compileProcessScope(scope)
+		if err != nil {
+			return nil, nil, fmt.Errorf("第 %d 个进程路径范围无效: %w", i+1, err)
+		}
+		cleaned = append(cleaned, scope)
+		compiled = append(compiled, compiledScope)
+	}
+	return cleaned, compiled, nil
+}
+
+// compileProcessScope 编译进程路径范围，路径通配符开头的 ~/ 展开为用户主目录
+func compileProcessScope(scope ProcessScope) (processScope, error) {
+	compiled := processScope{text: scope.Process}
+	if scope.Process == "" {
+		return compiled, fmt.Errorf("缺少进程通配符")
+	}
+
+	var err error
+	if compiled.process, err = compilePattern(scope.Process, false); err != nil {
+		return compiled, err
+	}
+	if compiled.include, err = compilePatterns(expandHomePatterns(scope.Include), true); err != nil {
+		return compiled, err
+	}
+	if compiled.exclude, err = compilePatterns(expandHomePatterns(scope.Exclude), true); err != nil {
+		return compiled, err
+	}
+	return compiled, nil
+}
+
+// expandHomePatterns 将通配符开头的 ~/ 展开为用户主目录
+// 使用sudo运行时展开为执行sudo的用户的主目录
+func expandHomePatterns(patterns []string) []string {
+	home := ""
+	expanded := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+			if home == "" {
+				home = homeDir()
+			}
+			pattern = filepath.Join(home, rest)
+			if strings.HasSuffix(rest, "/") {
+				pattern += "/"
+			}
+		}
+		expanded = append(expanded, pattern)
+	}
+	return expanded
+}
+
+// homeDir 返回用户主目录，使用sudo运行时返回执行sudo的用户的主目录
+func homeDir() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		if u, err := user.Lookup(name); err == nil {
+			return u.HomeDir
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return "/"
+}
+
+// matchScope 返回第一个进程名匹配的路径范围的序号，以及访问记录是否在范围内
+// 没有范围匹配时返回-1并记录，路径范围匹配源路径或目标路径中的任意一个即可
+func (f *filterSet) matchScope(access *database.FileAccess) (int, bool) {
+	for i, scope := range f.scopes {
+		if !scope.process.match(access.ProcessName) {
+			continue
+		}
+
+		if scope.matchPath(access.FilePath) {
+			return i, true
+		}
+		return i, access.TargetPath != "" && scope.matchPath(access.TargetPath)
+	}
+	return -1, true
+}
+
+// matchPath 判断路径是否在进程的路径范围内
+func (s *processScope) matchPath(path string) bool {
+	if len(s.include) > 0 && !matchAnyPattern(path, s.include) {
+		return false
+	}
+	return !matchAnyPattern(path, s.exclude)
+}
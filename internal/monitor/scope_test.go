@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"os/user"
+	"reflect"
+	"testing"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+func TestProcessScopes(t *testing.T) {
+	t.Setenv("SUDO_USER", "")
+	t.Setenv("HOME", "/home/u")
+	if err := SetProcessScopes([]ProcessScope{
+		{Process: "{ssh-agent,gpg*}"},
+		{Process: "*", Include: []string{"~/.ssh/**"}, Exclude: []string{"**/known_hosts"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetProcessScopes()
+
+	tests := []struct {
+		process string
+		path    string
+		target  string
+		index   int
+		tracked bool
+	}{
+		{"ssh-agent", "/srv/keys/id_rsa", "", 0, true},
+		{"gpg-agent", "/home/u/.gnupg/pubring.kbx", "", 0, true},
+		{"ssh", "/home/u/.ssh/id_ed25519", "", 1, true},
+		{"ssh", "/home/u/.ssh/known_hosts", "", 1, false},
+		{"cat", "/home/u/notes.txt", "", 1, false},
+		{"mv", "/home/u/id_rsa", "/home/u/.ssh/id_rsa", 1, true},
+	}
+
+	filters := currentFilters()
+	for _, tc := range tests {
+		access := database.FileAccess{ProcessName: tc.process, FilePath: tc.path, TargetPath: tc.target}
+		index, tracked := filters.matchScope(&access)
+		if index != tc.index || tracked != tc.tracked {
+			t.Errorf("%s %s: index = %d, tracked = %v, want %d, %v", tc.process, tc.path, index, tracked, tc.index, tc.tracked)
+		}
+	}
+}
+
+func TestProcessScopesFirstMatch(t *testing.T) {
+	t.Setenv("SUDO_USER", "")
+	t.Setenv("HOME", "/home/u")
+	if err := SetProcessScopes([]ProcessScope{
+		{Process: "make", Include: []string{"/src/**"}},
+		{Process: "m*", Include: []string{"/srv/**"}},
+		{Process: "regex:^node$", Exclude: []string{"**/node_modules/**"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetProcessScopes()
+
+	tests := []struct {
+		process string
+		path    string
+		index   int
+		tracked bool
+	}{
+		// 只使用第一个匹配的范围，后面的范围即使包含该路径也不生效
+		{"make", "/src/app/main.go", 0, true},
+		{"make", "/srv/www/index.html", 0, false},
+		{"mv", "/srv/www/index.html", 1, true},
+		{"mv", "/src/app/main.go", 1, false},
+		{"node", "/src/app/node_modules/x/index.js", 2, false},
+		{"node", "/etc/hosts", 2, true},
+		{"nodejs", "/etc/hosts", -1, true},
+	}
+
+	filters := currentFilters()
+	for _, tc := range tests {
+		access := database.FileAccess{ProcessName: tc.process, FilePath: tc.path}
+		index, tracked := filters.matchScope(&access)
+		if index != tc.index || tracked != tc.tracked {
+			t.Errorf("%s %s: index = %d, tracked = %v, want %d, %v", tc.process, tc.path, index, tracked, tc.index, tc.tracked)
+		}
+	}
+}
+
+func TestExpandHomePatterns(t *testing.T) {
+	root, err := user.Lookup("root")
+	if err != nil {
+		t.Skip("找不到root用户: ", err)
+	}
+
+	tests := []struct {
+		name     string
+		sudoUser string
+		patterns []string
+		want     []string
+	}{
+		{"home", "", []string{"~/.ssh/**", "~/docs/", "/etc/~/x", "~user/x"}, []string{"/home/u/.ssh/**", "/home/u/docs/", "/etc/~/x", "~user/x"}},
+		{"sudo user", root.Username, []string{"~/.ssh/**"}, []string{root.HomeDir + "/.ssh/**"}},
+		{"unknown sudo user", "no-such-user-fw", []string{"~/.ssh/**"}, []string{"/home/u/.ssh/**"}},
+	}
+
+	for _, tc := range tests {
+		t.Setenv("HOME", "/home/u")
+		t.Setenv("SUDO_USER", tc.sudoUser)
+		if got := expandHomePatterns(tc.patterns); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// SessionOptions 启动监控会话时的全部设置，各字段的含义与对应的 Set 函数相同
+type SessionOptions struct {
+	IncludePatterns []string
+	ExcludePatterns []string
+	ProcessPatterns []string
+	Rules           []string
+	ProcessScopes   []ProcessScope // 为nil时保留之前设置的进程路径范围
+	Filter          string
+	Ignore          IgnoreOptions
+	IgnoreFiles     []string
+	Operations      []string
+	Categories      []string
+	DeletionAudit   bool
+	Source          string // 为空时使用当前平台的默认事件源
+	FSUsage         FSUsageOptions
+	Strace          StraceOptions
+	Capture         bool
+}
+
+// Session 编译和校验过的监控会话设置
+// 准备时不修改当前的设置，事件源启动成功后一次性发布，校验或启动失败时当前的设置保持不变
+type Session struct {
+	filters        filterSet
+	replaceScopes  bool
+	scopes         []ProcessScope
+	ignore         IgnoreOptions
+	ignoreCounters map[string]*atomic.Int64
+	operations     map[string]bool
+	categories     map[string]bool
+	deletionAudit  bool
+	sourceName     string
+	fsUsage        FSUsageOptions
+	strace         StraceOptions
+	capture        bool
+}
+
+// PrepareSession 编译并校验监控会话的全部设置，任意一项无效时返回错误
+func PrepareSession(options SessionOptions) (*Session, error) {
+	s := &Session{
+		deletionAudit: options.DeletionAudit,
+		sourceName:    options.Source,
+		strace:        options.Strace,
+		capture:       options.Capture,
+	}
+	f := &s.filters
+
+	var err error
+	f.includePatterns = cleanPatterns(options.IncludePatterns)
+	if f.include, err = compilePatterns(f.includePatterns, true); err != nil {
+		return nil, fmt.Errorf("包含目录通配符无效: %w", err)
+	}
+	f.excludePatterns = cleanPatterns(options.ExcludePatterns)
+	if f.exclude, err = compilePatterns(f.excludePatterns, true); err != nil {
+		return nil, fmt.Errorf("排除目录通配符无效: %w", err)
+	}
+	f.processPatterns = cleanPatterns(options.ProcessPatterns)
+	if f.process, err = compilePatterns(f.processPatterns, false); err != nil {
+		return nil, fmt.Errorf("进程通配符无效: %w", err)
+	}
+
+	if f.rules, err = compileFilterRules(options.Rules); err != nil {
+		return nil, err
+	}
+	if options.ProcessScopes != nil {
+		s.replaceScopes = true
+		if s.scopes, f.scopes, err = compileProcessScopes(options.ProcessScopes); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.expression = strings.TrimSpace(options.Filter); f.expression != "" {
+		if f.capture, err = CompileFilter(f.expression); err != nil {
+			return nil, fmt.Errorf("过滤表达式无效: %w", err)
+		}
+	}
+
+	// 忽略规则的计数器在会话开始时清空
+	ignoreMu.Lock()
+	s.ignore, err = cleanIgnoreOptions(options.Ignore)
+	if err == nil {
+		s.ignoreCounters = make(map[string]*atomic.Int64)
+		f.ignore = buildIgnoreRules(s.ignore, s.ignoreCounters)
+	}
+	ignoreMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.ignoreFiles = cleanPatterns(options.IgnoreFiles); len(f.ignoreFiles) > 0 {
+		if f.gitignore, err = loadGitignore(f.ignoreFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.operations, s.categories, err = compileTrackedOperations(options.Operations, options.Categories); err != nil {
+		return nil, err
+	}
+	if s.fsUsage, err = cleanFSUsageOptions(options.FSUsage); err != nil {
+		return nil, err
+	}
+
+	// 提前创建一次事件源，确认事件源存在、当前平台支持且配置有效
+	if _, err := newEventSource(s.sourceName, s.sourceConfig()); err != nil {
+		return nil, err
+	}
+
+	name := s.sourceName
+	if name == "" {
+		name = defaultSourceName()
+	}
+	// 只有fs_usage事件源有原始输出可以保存
+	if s.capture && name != SourceFSUsage {
+		return nil, fmt.Errorf("capture只支持fs_usage事件源，当前事件源: %s", name)
+	}
+	// fanotify不报告删除和重命名事件，删除审计模式下不会记录任何内容
+	if s.deletionAudit && name == SourceFanotify {
+		return nil, errors.New("fanotify事件源不支持删除审计，请使用inotify或strace事件源")
+	}
+
+	return s, nil
+}
+
+// sourceConfig 返回会话的事件源配置
+func (s *Session) sourceConfig() sourceConfig {
+	return sourceConfig{
+		roots:   patternRoots(s.filters.includePatterns),
+		fsUsage: s.fsUsage,
+		strace:  s.strace,
+		capture: s.capture,
+	}
+}
+
+// Start 启动会话的事件源，启动成功后发布会话的全部设置，启动失败时不修改当前的设置
+func (s *Session) Start() (EventSource, error) {
+	source, err := startEventSource(s.sourceName, s.sourceConfig())
+	if err != nil {
+		return nil, err
+	}
+	s.publish(source)
+	return source, nil
+}
+
+// publish 将会话的设置替换为当前的设置
+func (s *Session) publish(source EventSource) {
+	scopeMu.Lock()
+	if s.replaceScopes {
+		processScopes = s.scopes
+	}
+	updateFilters(func(f *filterSet) {
+		// 未指定进程路径范围时保留之前设置的范围
+		scopes := f.scopes
+		*f = s.filters
+		if !s.replaceScopes {
+			f.scopes = scopes
+		}
+	})
+	scopeMu.Unlock()
+
+	ignoreMu.Lock()
+	ignoreOptions = s.ignore
+	ignoreCounters = s.ignoreCounters
+	ignoreMu.Unlock()
+
+	trackedOperations, trackedCategories = s.operations, s.categories
+	deletionAudit = s.deletionAudit
+	fsUsageOptions = s.fsUsage
+	straceOptions = s.strace
+	captureEnabled = s.capture
+
+	// 没有权限使用fanotify时改用了inotify
+	sourceName = s.sourceName
+	if source.Name() != GetEventSource() {
+		sourceName = source.Name()
+	}
+	setSourceRoots(source)
+
+	log.Printf("已设置监控会话，事件源: %s, 包含路径通配符: %s, 排除路径通配符: %s, 进程通配符: %s",
+		source.Name(), GetIncludePattern(), GetExcludePattern(), GetProcessPattern())
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrepareSessionInvalid(t *testing.T) {
+	if err := SetIncludePattern("/keep/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetIncludePattern()
+	if err := SetFilterRules("- **/keep/**"); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetFilterRules()
+
+	strace := StraceOptions{File: "/data/trace.log"}
+	tests := []struct {
+		name    string
+		options SessionOptions
+	}{
+		{"include pattern", SessionOptions{IncludePatterns: []string{"/src/**", "regex:("}, Source: SourceStrace, Strace: strace}},
+		{"process pattern", SessionOptions{ProcessPatterns: []string{"[a-"}, Source: SourceStrace, Strace: strace}},
+		{"rule", SessionOptions{Rules: []string{"path:/src/**"}, Source: SourceStrace, Strace: strace}},
+		{"process scope", SessionOptions{ProcessScopes: []ProcessScope{{Process: ""}}, Source: SourceStrace, Strace: strace}},
+		{"filter expression", SessionOptions{Filter: "path ==", Source: SourceStrace, Strace: strace}},
+		{"ignore profile", SessionOptions{Ignore: IgnoreOptions{Profile: "missing"}, Source: SourceStrace, Strace: strace}},
+		{"operation category", SessionOptions{Categories: []string{"rename"}, Source: SourceStrace, Strace: strace}},
+		{"fs_usage mode", SessionOptions{FSUsage: FSUsageOptions{Modes: []string{"network"}}, Source: SourceStrace, Strace: strace}},
+		{"unknown source", SessionOptions{Source: "dtrace"}},
+		{"strace options", SessionOptions{Source: SourceStrace}},
+		{"capture", SessionOptions{Capture: true, Source: SourceStrace, Strace: strace}},
+		{"deletion audit with fanotify", SessionOptions{DeletionAudit: true, Source: SourceFanotify}},
+	}
+
+	for _, tc := range tests {
+		if _, err := PrepareSession(tc.options); err == nil {
+			t.Errorf("%s: PrepareSession succeeded, want error", tc.name)
+		}
+		if got := GetIncludePatterns(); !reflect.DeepEqual(got, []string{"/keep/**"}) {
+			t.Errorf("%s: include patterns changed to %q", tc.name, got)
+		}
+		if got := GetFilterRules(); !reflect.DeepEqual(got, []string{"- **/keep/**"}) {
+			t.Errorf("%s: rules changed to %q", tc.name, got)
+		}
+	}
+}
+
+func TestSessionPublish(t *testing.T) {
+	defer func() {
+		ResetIncludePattern()
+		ResetExcludePattern()
+		ResetProcessPattern()
+		ResetFilterRules()
+		ResetFilterExpression()
+		ResetIgnoreOptions()
+		ResetTrackedOperations()
+		ResetDeletionAudit()
+		ResetEventSource()
+		ResetStraceOptions()
+	}()
+	if err := SetProcessScopes([]ProcessScope{{Process: "ssh", Include: []string{"/home/u/.ssh/**"}}}); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetProcessScopes()
+
+	strace := StraceOptions{File: "/data/trace.log"}
+	session, err := PrepareSession(SessionOptions{
+		IncludePatterns: []string{"/src/**", " ", "/src/**"},
+		ExcludePatterns: []string{"**/vendor/**"},
+		ProcessPatterns: []string{"go"},
+		Rules:           []string{"- **/build/**"},
+		Filter:          `op == "open"`,
+		Ignore:          IgnoreOptions{Profile: NoIgnoreProfile},
+		Operations:      []string{"open", "unlink"},
+		DeletionAudit:   true,
+		Source:          SourceStrace,
+		Strace:          strace,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := GetIncludePatterns(); len(got) != 0 {
+		t.Fatalf("PrepareSession published include patterns %q", got)
+	}
+
+	source, err := NewStraceSource(strace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.publish(source)
+
+	operations, _ := GetTrackedOperations()
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"include", GetIncludePatterns(), []string{"/src/**"}},
+		{"exclude", GetExcludePatterns(), []string{"**/vendor/**"}},
+		{"process", GetProcessPatterns(), []string{"go"}},
+		{"rules", GetFilterRules(), []string{"- **/build/**"}},
+		{"filter", GetFilterExpression(), `op == "open"`},
+		{"ignore", GetIgnoreOptions().Profile, NoIgnoreProfile},
+		{"ignore rules", len(currentFilters().ignore), 0},
+		{"operations", operations, []string{"open", "unlink"}},
+		{"deletion audit", GetDeletionAudit(), true},
+		{"source", GetEventSource(), SourceStrace},
+		{"strace", GetStraceOptions(), strace},
+		{"process scopes kept", len(currentFilters().scopes), 1},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// EventSource 文件访问事件源
+// 事件源负责采集并解析文件访问事件，解析后的记录通过Events通道交给
+// 监控管道统一去重、批处理并写入存储
+type EventSource interface {
+	// Name 返回事件源名称
+	Name() string
+
+	// Start 启动事件源
+	Start() error
+
+	// Stop 停止事件源
+	Stop() error
+
+	// Events 返回访问记录通道，事件源结束时关闭该通道
+	Events() <-chan database.FileAccess
+
+	// Errors 返回事件源运行期间产生的错误，事件源结束时关闭该通道
+	Errors() <-chan error
+}
+
+// rootedSource 只监控指定目录的事件源，监控的目录在启动时确定
+type rootedSource interface {
+	Roots() []string
+}
+
+// ErrRootsChanged 运行中替换的包含目录通配符超出了事件源启动时监控的目录
+var ErrRootsChanged = errors.New("包含目录超出了启动时监控的目录")
+
+// 事件源名称
+const (
+	SourceFSUsage  = "fs_usage"
+	SourceFanotify = "fanotify"
+	SourceInotify  = "inotify"
+	SourceStrace   = "strace"
+)
+
+// UnknownProcess 无法获取进程信息时使用的进程名
+const UnknownProcess = "(unknown)"
+
+// sourceConfig 创建事件源时使用的会话设置
+type sourceConfig struct {
+	roots   []string // fanotify和inotify监控的目录
+	fsUsage FSUsageOptions
+	strace  StraceOptions
+	capture bool
+}
+
+// currentSourceConfig 返回当前会话设置对应的事件源配置
+func currentSourceConfig() sourceConfig {
+	return sourceConfig{
+		roots:   patternRoots(GetIncludePatterns()),
+		fsUsage: fsUsageOptions,
+		strace:  straceOptions,
+		capture: captureEnabled,
+	}
+}
+
+// sourceFactories 已注册的事件源构造函数
+var sourceFactories = map[string]func(sourceConfig) (EventSource, error){
+	SourceFSUsage: func(config sourceConfig) (EventSource, error) {
+		return newFSUsageSource(fsUsageArgs(config.fsUsage), config.capture), nil
+	},
+	SourceFanotify: newFanotifySource,
+	SourceInotify:  newInotifySource,
+	SourceStrace:   newStraceSource,
+}
+
+// 全局变量，用于存储当前选择的事件源名称
+var sourceName string
+
+// 全局变量，用于存储运行中的事件源监控的目录，事件源不限制目录或未运行时为空
+var sourceRoots []string
+
+// NewEventSource 根据名称和当前会话的设置创建事件源，名称为空时使用当前平台的默认事件源
+func NewEventSource(name string) (EventSource, error) {
+	return newEventSource(name, currentSourceConfig())
+}
+
+// newEventSource 根据名称和指定的设置创建事件源
+func newEventSource(name string, config sourceConfig) (EventSource, error) {
+	if name == "" {
+		name = defaultSourceName()
+	}
+
+	factory, ok := sourceFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的事件源: %s", name)
+	}
+	return factory(config)
+}
+
+// defaultSourceName 返回当前平台的默认事件源名称
+func defaultSourceName() string {
+	if runtime.GOOS == "linux" {
+		return SourceFanotify
+	}
+	return SourceFSUsage
+}
+
+// SetEventSource 设置开始监控时使用的事件源
+func SetEventSource(name string) error {
+	if name == "" {
+		ResetEventSource()
+		return nil
+	}
+
+	// 提前创建一次，确认事件源存在且当前平台支持
+	if _, err := NewEventSource(name); err != nil {
+		return err
+	}
+
+	sourceName = name
+	log.Printf("已设置事件源: %s", name)
+	return nil
+}
+
+// GetEventSource 获取当前选择的事件源名称
+func GetEventSource() string {
+	if sourceName == "" {
+		return defaultSourceName()
+	}
+	return sourceName
+}
+
+// ResetEventSource 重置为当前平台的默认事件源
+func ResetEventSource() {
+	sourceName = ""
+	sourceRoots = nil
+	log.Println("已重置事件源")
+}
+
+// GetMonitorCommand 返回当前事件源对应的采集方式说明
+func GetMonitorCommand() string {
+	switch GetEventSource() {
+	case SourceFanotify:
+		return "fanotify " + strings.Join(monitoredRoots(), " ")
+	case SourceInotify:
+		return "inotify " + strings.Join(monitoredRoots(), " ") + " (无进程信息)"
+	case SourceStrace:
+		return "strace " + strings.Join(GetStraceArgs(), " ")
+	default:
+		if captureEnabled {
+			return GetFSUsageCommand() + " | tee " + filepath.Join(captureDir, capturePrefix+"*"+captureSuffix)
+		}
+		return GetFSUsageCommand()
+	}
+}
+
+// monitoredRoots 返回事件源监控的目录，运行中为启动时确定的目录，未运行时根据包含目录通配符推导
+func monitoredRoots() []string {
+	if sourceRoots != nil {
+		return sourceRoots
+	}
+	return patternRoots(GetIncludePatterns())
+}
+
+// checkSourceRoots 检查包含目录通配符推导出的目录是否都在运行中的事件源监控的目录之内
+func checkSourceRoots(includePatterns []string) error {
+	if sourceRoots == nil {
+		return nil
+	}
+
+	for _, root := range patternRoots(includePatterns) {
+		covered := false
+		for _, kept := range sourceRoots {
+			if isSubPath(root, kept) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return fmt.Errorf("%w: %s 不在 %s 之内，需要重新启动监控", ErrRootsChanged, root, strings.Join(sourceRoots, " "))
+		}
+	}
+	return nil
+}
+
+// patternRoots 根据包含目录通配符推导出需要监控的根目录
+// 取每个通配符中第一个通配字符之前的目录部分，去掉位于其他根目录之下的目录
+// 未设置通配符时监控整个文件系统
+func patternRoots(includePatterns []string) []string {
+	if len(includePatterns) == 0 {
+		return []string{"/"}
+	}
+
+	roots := make([]string, 0, len(includePatterns))
+	for _, pattern := range includePatterns {
+		roots = append(roots, patternRoot(pattern))
+	}
+	sort.Strings(roots)
+
+	var result []string
+	for _, root := range roots {
+		nested := false
+		for _, kept := range result {
+			if isSubPath(root, kept) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			result = append(result, root)
+		}
+	}
+	return result
+}
+
+// isSubPath 判断path是否等于dir或位于dir之下
+func isSubPath(path, dir string) bool {
+	if path == dir || dir == "/" {
+		return true
+	}
+	return strings.HasPrefix(path, dir+"/")
+}
+
+// patternRoot 返回模式中不包含通配字符的最长目录前缀
+// 正则表达式只有以 ^ 开头时才能确定前缀
+func patternRoot(pattern string) string {
+	var static string
+	if expr, ok := strings.CutPrefix(pattern, patternPrefixRegex); ok {
+		if expr, ok = strings.CutPrefix(expr, "^"); ok {
+			if re, err := regexp.Compile(expr); err == nil {
+				static, _ = re.LiteralPrefix()
+			}
+		}
+	} else {
+		static = strings.TrimPrefix(pattern, patternPrefixGlob)
+		if idx := strings.IndexAny(static, "*?[{\\"); idx >= 0 {
+			static = static[:idx]
+		}
+	}
+
+	if !strings.HasPrefix(static, "/") {
+		return "/"
+	}
+
+	// 只保留完整的目录部分
+	if !strings.HasSuffix(static, "/") {
+		static = filepath.Dir(static)
+	}
+	return filepath.Clean(static)
+}
@@ -0,0 +1,877 @@
+package monitor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+// StraceOptions strace事件源的配置，Command、PID、File三者只能设置一个
+type StraceOptions struct {
+	Command []string `json:"command"` // 在strace下运行的命令
+	PID     int      `json:"pid"`     // 附加到已运行的进程
+	File    string   `json:"file"`    // 读取已保存的strace输出文件
+	Date    string   `json:"date"`    // 输出文件使用 -tt 格式时跟踪开始的日期（YYYY-MM-DD），-ttt 格式不需要
+}
+
+// 全局变量，用于存储strace事件源的配置
+var straceOptions StraceOptions
+
+// straceOperations strace系统调用名与操作类型的对应关系
+var straceOperations = map[string]string{
+	"openat":     "open",
+	"openat2":    "open",
+	"creat":      "create",
+	"pread64":    "pread",
+	"pwrite64":   "pwrite",
+	"preadv":     "readv",
+	"pwritev":    "writev",
+	"unlinkat":   "unlink",
+	"renameat":   "rename",
+	"renameat2":  "rename",
+	"linkat":     "link",
+	"symlinkat":  "symlink",
+	"newfstatat": "stat",
+	"fstatat64":  "stat",
+}
+
+// StraceSource 解析strace输出的事件源，可以实时运行strace，也可以读取已保存的输出文件
+type StraceSource struct {
+	options StraceOptions
+	cmd     *exec.Cmd
+	file    *os.File // 输出文件，实时运行时为读取strace输出的管道
+	parser  *straceParser
+	events  chan database.FileAccess
+	errors  chan error
+}
+
+// NewStraceSource 创建strace事件源
+func NewStraceSource(options StraceOptions) (*StraceSource, error) {
+	set := 0
+	if len(options.Command) > 0 {
+		set++
+	}
+	if options.PID > 0 {
+		set++
+	}
+	if options.File != "" {
+		set++
+	}
+	if set != 1 {
+		return nil, errors.New("strace事件源需要且只能指定命令、进程PID或输出文件中的一个")
+	}
+
+	if options.File == "" && runtime.GOOS != "linux" {
+		return nil, errors.New("实时运行strace仅支持Linux，其他平台请指定输出文件")
+	}
+
+	if options.Date != "" {
+		if options.File == "" {
+			return nil, errors.New("date只用于读取strace输出文件")
+		}
+		if _, err := time.ParseInLocation(straceDateLayout, options.Date, time.Local); err != nil {
+			return nil, fmt.Errorf("date格式应为YYYY-MM-DD: %w", err)
+		}
+	}
+
+	return &StraceSource{
+		options: options,
+		events:  make(chan database.FileAccess, batchSize),
+		errors:  make(chan error, 1),
+	}, nil
+}
+
+// newStraceSource 根据会话的strace配置创建事件源
+func newStraceSource(config sourceConfig) (EventSource, error) {
+	return NewStraceSource(config.strace)
+}
+
+// Name 返回事件源名称
+func (s *StraceSource) Name() string {
+	return SourceStrace
+}
+
+// Start 启动strace或打开输出文件并开始解析
+func (s *StraceSource) Start() error {
+	var reader io.Reader
+
+	if s.options.File != "" {
+		file, err := os.Open(s.options.File)
+		if err != nil {
+			return fmt.Errorf("打开strace输出文件失败: %w", err)
+		}
+
+		// -tt 格式只有时刻，需要指定跟踪开始的日期，文件修改时间是跟踪结束的时间，不能作为基准
+		base, err := straceFileBase(file, s.options.Date)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		s.file = file
+		s.parser = newStraceParser(base, false)
+		reader = file
+	} else {
+		pipeReader, pipeWriter, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("创建管道失败: %w", err)
+		}
+
+		// strace的输出通过 -o /dev/fd/3 写入单独的管道，被跟踪命令写到标准错误的内容不会混入
+		// strace自身和被跟踪命令的标准错误输出到服务的日志
+		s.cmd = exec.Command("strace", straceArgs(s.options)...)
+		s.cmd.ExtraFiles = []*os.File{pipeWriter}
+		s.cmd.Stderr = os.Stderr
+		if err := s.cmd.Start(); err != nil {
+			pipeReader.Close()
+			pipeWriter.Close()
+			return fmt.Errorf("启动strace命令失败: %w", err)
+		}
+		pipeWriter.Close()
+
+		s.file = pipeReader
+		s.parser = newStraceParser(time.Now(), true)
+		reader = pipeReader
+	}
+
+	go func() {
+		defer close(s.events)
+		defer close(s.errors)
+
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if access := s.parser.parseLine(scanner.Text()); access != nil {
+				s.events <- *access
+			}
+		}
+
+		if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+			s.errors <- fmt.Errorf("读取strace输出失败: %w", err)
+		}
+
+		if s.cmd != nil {
+			s.cmd.Wait()
+		}
+	}()
+
+	return nil
+}
+
+// straceDateLayout 指定strace输出文件开始日期的格式
+const straceDateLayout = "2006-01-02"
+
+// straceFileBase 返回解析strace输出文件时刻使用的日期
+// 指定了date时使用该日期；未指定时只接受 -ttt 格式的Unix时间，-tt 格式返回错误
+func straceFileBase(file *os.File, date string) (time.Time, error) {
+	if date != "" {
+		return time.ParseInLocation(straceDateLayout, date, time.Local)
+	}
+
+	// 找到第一个带时间戳的行判断格式，读取后回到文件开头
+	defer file.Seek(0, io.SeekStart)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		_, rest := splitStracePID(scanner.Text())
+		field, _, _ := strings.Cut(rest, " ")
+		if _, ok := parseClock(field); ok {
+			return time.Time{}, errors.New("strace输出文件使用 -tt 格式，只包含时刻，请通过date指定跟踪开始的日期，或使用 -ttt 输出Unix时间")
+		}
+		if _, err := strconv.ParseFloat(field, 64); err == nil && strings.Contains(field, ".") {
+			break
+		}
+	}
+	return time.Time{}, nil
+}
+
+// Stop 停止strace命令或关闭输出文件
+// 被跟踪的命令会继承管道，strace退出后可能仍未关闭，先关闭读取端结束解析
+func (s *StraceSource) Stop() error {
+	if s.file != nil {
+		s.file.Close()
+	}
+
+	if s.cmd == nil || s.cmd.Process == nil {
+		return nil
+	}
+
+	if err := s.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("停止strace命令失败: %w", err)
+	}
+	return nil
+}
+
+// Offline 读取已保存的输出文件时，记录中的进程可能已经不存在
+func (s *StraceSource) Offline() bool {
+	return s.options.File != ""
+}
+
+// Events 返回访问记录通道
+func (s *StraceSource) Events() <-chan database.FileAccess {
+	return s.events
+}
+
+// Errors 返回错误通道
+func (s *StraceSource) Errors() <-chan error {
+	return s.errors
+}
+
+// straceParser 解析strace -f -tt 的输出
+// 记录每个进程的文件描述符和进程名，用于还原只包含fd的read/write调用
+// strace -f 输出的是线程ID，通过clone调用或/proc还原线程所属的进程
+// 记录每个进程的工作目录，用于还原相对路径
+type straceParser struct {
+	clock      *clockTracker
+	live       bool
+	names      map[int]string
+	fds        map[int]map[int]string
+	cwds       map[int]string
+	threads    map[int]int
+	unfinished map[int]string
+}
+
+// newStraceParser 创建strace输出解析器，live表示被跟踪的进程仍在运行，可以查询/proc
+func newStraceParser(base time.Time, live bool) *straceParser {
+	return &straceParser{
+		clock:      newClockTracker(base),
+		live:       live,
+		names:      make(map[int]string),
+		fds:        make(map[int]map[int]string),
+		cwds:       make(map[int]string),
+		threads:    make(map[int]int),
+		unfinished: make(map[int]string),
+	}
+}
+
+// parseLine 解析strace输出的单行
+func (p *straceParser) parseLine(line string) *database.FileAccess {
+	tid, rest := splitStracePID(line)
+
+	// 提取时间戳，-tt 输出时刻，-ttt 输出Unix时间
+	timestamp := time.Now()
+	if fields := strings.SplitN(rest, " ", 2); len(fields) == 2 {
+		if t, ok := p.parseTimestamp(fields[0]); ok {
+			timestamp = t
+			rest = strings.TrimLeft(fields[1], " ")
+		}
+	}
+
+	// 进程退出后清理状态，主线程退出时产生进程退出事件
+	if strings.HasPrefix(rest, "+++ exited") || strings.HasPrefix(rest, "+++ killed") {
+		pid := p.threadGroup(tid)
+		processName := p.processName(pid)
+		delete(p.names, tid)
+		delete(p.fds, tid)
+		delete(p.cwds, tid)
+		delete(p.threads, tid)
+		delete(p.unfinished, tid)
+
+		if tid == 0 || pid != tid {
+			return nil
+		}
+		return &database.FileAccess{
+			Timestamp:   timestamp,
+			ProcessName: processName,
+			PID:         pid,
+			Operation:   OperationExit,
+		}
+	}
+
+	// 信号等其他信息
+	if strings.HasPrefix(rest, "---") || strings.HasPrefix(rest, "+++") {
+		return nil
+	}
+
+	// 被其他线程打断的调用会拆成两行，先保存前半部分
+	if idx := strings.Index(rest, " <unfinished ...>"); idx >= 0 {
+		p.unfinished[tid] = rest[:idx]
+		return nil
+	}
+	if strings.HasPrefix(rest, "<... ") {
+		idx := strings.Index(rest, " resumed>")
+		head, ok := p.unfinished[tid]
+		if idx < 0 || !ok {
+			return nil
+		}
+		delete(p.unfinished, tid)
+		rest = head + rest[idx+len(" resumed>"):]
+	}
+
+	name, args, result, ok := splitStraceCall(rest)
+	if !ok {
+		return nil
+	}
+
+	return p.handleCall(tid, timestamp, name, args, result)
+}
+
+// parseTimestamp 解析 -tt 或 -ttt 格式的时间戳
+func (p *straceParser) parseTimestamp(field string) (time.Time, bool) {
+	if strings.Contains(field, ":") {
+		return p.clock.resolve(field)
+	}
+
+	// 秒和小数部分分别解析，float64无法精确表示微秒级的Unix时间
+	secText, fracText, ok := strings.Cut(field, ".")
+	if !ok || fracText == "" || len(fracText) > 9 {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(secText, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	frac, err := strconv.ParseUint(fracText, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for i := len(fracText); i < 9; i++ {
+		frac *= 10
+	}
+	return time.Unix(seconds, int64(frac)), true
+}
+
+// handleCall 处理一次完整的系统调用，tid为strace输出的线程ID，name为系统调用名
+func (p *straceParser) handleCall(tid int, timestamp time.Time, name string, args []string, result string) *database.FileAccess {
+	// 线程共享所属进程的文件描述符表、工作目录和进程名
+	pid := p.threadGroup(tid)
+
+	operation := name
+	if op, ok := straceOperations[name]; ok {
+		operation = op
+	}
+
+	// 调用成功时的返回值
+	ret, succeeded := straceReturnValue(result)
+
+	var filePath, targetPath string
+	switch name {
+	case "clone", "clone3", "fork", "vfork":
+		if !succeeded || ret == 0 {
+			return nil
+		}
+
+		// 记录新线程所属的进程
+		if strings.Contains(strings.Join(args, ","), "CLONE_THREAD") {
+			p.threads[ret] = pid
+			return nil
+		}
+
+		// 新进程在exec之前沿用父进程的名称，并继承工作目录
+		processName := p.processName(pid)
+		p.threads[ret] = ret
+		p.names[ret] = processName
+		if cwd, ok := p.cwds[pid]; ok {
+			p.cwds[ret] = cwd
+		}
+		return &database.FileAccess{
+			Timestamp:   timestamp,
+			ProcessName: processName,
+			PID:         ret,
+			PPID:        pid,
+			Operation:   OperationFork,
+		}
+
+	case "execve", "execveat":
+		// 记录程序名，后续调用使用该名称作为进程名
+		path := p.resolvePath(pid, "", straceStringArg(args, 0))
+		if name == "execveat" {
+			path = p.resolvePath(pid, straceArg(args, 0), straceStringArg(args, 1))
+		}
+		if path == "" {
+			return nil
+		}
+		if succeeded {
+			p.names[pid] = filepath.Base(path)
+		}
+
+		// exec事件用于维护进程表，在管道中再按会话条件过滤
+		return &database.FileAccess{
+			Timestamp:   timestamp,
+			ProcessName: p.processName(pid),
+			PID:         pid,
+			TID:         tid,
+			FilePath:    path,
+			Operation:   name,
+			Duration:    straceDuration(result),
+			Errno:       straceErrno(result),
+		}
+
+	case "openat", "openat2", "unlinkat", "newfstatat", "fstatat64", "statx", "mkdirat", "mknodat",
+		"faccessat", "faccessat2", "fchmodat", "fchownat", "readlinkat", "utimensat":
+		// 第一个参数是目录描述符
+		filePath = p.resolvePath(pid, straceArg(args, 0), straceStringArg(args, 1))
+
+	case "rename", "link":
+		filePath = p.resolvePath(pid, "", straceStringArg(args, 0))
+		targetPath = p.resolvePath(pid, "", straceStringArg(args, 1))
+
+	case "renameat", "renameat2", "linkat":
+		// 源路径和目标路径前各有一个目录描述符
+		filePath = p.resolvePath(pid, straceArg(args, 0), straceStringArg(args, 1))
+		targetPath = p.resolvePath(pid, straceArg(args, 2), straceStringArg(args, 3))
+
+	case "symlink":
+		// 符号链接的内容按原样记录
+		filePath = straceStringArg(args, 0)
+		targetPath = p.resolvePath(pid, "", straceStringArg(args, 1))
+
+	case "symlinkat":
+		filePath = straceStringArg(args, 0)
+		targetPath = p.resolvePath(pid, straceArg(args, 1), straceStringArg(args, 2))
+
+	case "chdir":
+		filePath = p.resolvePath(pid, "", straceStringArg(args, 0))
+		if succeeded && filePath != "" {
+			p.cwds[pid] = filePath
+		}
+
+	case "fchdir":
+		filePath = p.resolveFD(pid, straceArg(args, 0))
+		if succeeded && strings.HasPrefix(filePath, "/") {
+			p.cwds[pid] = filePath
+		}
+
+	default:
+		if len(args) > 0 && strings.HasPrefix(args[0], "\"") {
+			filePath = p.resolvePath(pid, "", straceStringArg(args, 0))
+		} else if len(args) > 0 {
+			// 第一个参数是文件描述符，-y 参数会以 3</path> 的形式给出路径
+			filePath = p.resolveFD(pid, args[0])
+		}
+	}
+
+	// -y 参数会在返回的描述符后附加完整路径，工作目录未知时使用该路径
+	if (operation == "open" || operation == "create") && succeeded && !strings.HasPrefix(filePath, "/") {
+		if path := straceResultPath(result); path != "" {
+			filePath = path
+		}
+	}
+
+	// 记录打开的文件描述符
+	if (operation == "open" || operation == "create") && succeeded && filePath != "" {
+		if p.fds[pid] == nil {
+			p.fds[pid] = make(map[int]string)
+		}
+		p.fds[pid][ret] = filePath
+	}
+
+	if operation == "close" && len(args) > 0 {
+		if fd, err := strconv.Atoi(straceFDNumber(args[0])); err == nil {
+			delete(p.fds[pid], fd)
+		}
+	}
+
+	// 没有路径的调用不记录，无法还原的相对路径按原样记录
+	if filePath == "" {
+		return nil
+	}
+
+	processName := p.processName(pid)
+	access := &database.FileAccess{
+		Timestamp:   timestamp,
+		ProcessName: processName,
+		PID:         pid,
+		TID:         tid,
+		FilePath:    filePath,
+		TargetPath:  targetPath,
+		Operation:   operation,
+		Duration:    straceDuration(result),
+		Errno:       straceErrno(result),
+	}
+
+	// 只记录会话关注的操作，进程和路径条件在管道中检查
+	if !isTrackedAccess(access, SourceStrace) {
+		return nil
+	}
+
+	// 打开文件时返回值为描述符，读写时返回值为字节数
+	if operation == "open" || operation == "create" {
+		if succeeded {
+			access.FD = ret
+		}
+	} else if len(args) > 0 {
+		if fd, err := strconv.Atoi(straceFDNumber(args[0])); err == nil {
+			access.FD = fd
+		}
+		if succeeded && (strings.Contains(operation, "read") || strings.Contains(operation, "write")) {
+			access.Bytes = int64(ret)
+		}
+	}
+
+	return access
+}
+
+// threadGroup 返回线程所属进程的PID
+func (p *straceParser) threadGroup(tid int) int {
+	if pid, ok := p.threads[tid]; ok {
+		return pid
+	}
+
+	pid := tid
+	if p.live {
+		if tgid := lookupThreadGroup(tid); tgid > 0 {
+			pid = tgid
+		}
+	}
+
+	p.threads[tid] = pid
+	return pid
+}
+
+// resolveFD 根据文件描述符参数还原文件路径
+func (p *straceParser) resolveFD(pid int, arg string) string {
+	// -y 参数输出的格式：3</path/to/file>
+	if path := straceFDPath(arg); path != "" {
+		return path
+	}
+
+	fd, err := strconv.Atoi(arg)
+	if err != nil {
+		return ""
+	}
+	return p.fds[pid][fd]
+}
+
+// resolvePath 将相对路径还原为绝对路径
+// dirArg 为 *at 调用的目录描述符参数，为空或AT_FDCWD时相对于进程的工作目录
+// 无法确定目录时按原样返回相对路径
+func (p *straceParser) resolvePath(pid int, dirArg string, path string) string {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	var dir string
+	if dirArg == "" || dirArg == "AT_FDCWD" {
+		dir = p.cwd(pid)
+	} else {
+		dir = p.resolveFD(pid, dirArg)
+	}
+	if !strings.HasPrefix(dir, "/") {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// cwd 返回进程的工作目录，优先使用chdir记录的目录，实时跟踪时查询/proc，都无法获取时返回空字符串
+func (p *straceParser) cwd(pid int) string {
+	if dir, ok := p.cwds[pid]; ok {
+		return dir
+	}
+
+	dir := ""
+	if p.live {
+		dir = lookupProcessCwd(pid)
+	}
+	if dir != "" {
+		p.cwds[pid] = dir
+	}
+	return dir
+}
+
+// processName 返回pid对应的进程名
+// 优先使用execve记录的程序名，实时跟踪时查询/proc，都无法获取时使用pid
+func (p *straceParser) processName(pid int) string {
+	if name, ok := p.names[pid]; ok {
+		return name
+	}
+
+	name := ""
+	if p.live {
+		name = lookupProcessName(pid)
+	}
+	if name == "" {
+		name = strconv.Itoa(pid)
+	}
+
+	p.names[pid] = name
+	return name
+}
+
+// splitStracePID 拆分行首的pid，支持 "1234 " 和 "[pid 1234] " 两种格式
+func splitStracePID(line string) (int, string) {
+	line = strings.TrimLeft(line, " ")
+
+	if strings.HasPrefix(line, "[pid ") {
+		end := strings.Index(line, "]")
+		if end < 0 {
+			return 0, line
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(line[len("[pid "):end]))
+		return pid, strings.TrimLeft(line[end+1:], " ")
+	}
+
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) == 2 {
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			return pid, strings.TrimLeft(fields[1], " ")
+		}
+	}
+
+	return 0, line
+}
+
+// splitStraceCall 将 "openat(AT_FDCWD, "/etc/hosts", O_RDONLY) = 3" 拆分为调用名、参数和返回值
+func splitStraceCall(call string) (string, []string, string, bool) {
+	open := strings.Index(call, "(")
+	if open <= 0 {
+		return "", nil, "", false
+	}
+	name := call[:open]
+	if strings.ContainsAny(name, " <>") {
+		return "", nil, "", false
+	}
+
+	eq := strings.LastIndex(call, ") = ")
+	if eq < open {
+		return "", nil, "", false
+	}
+
+	return name, splitStraceArgs(call[open+1 : eq]), strings.TrimSpace(call[eq+len(") = "):]), true
+}
+
+// splitStraceArgs 按顶层逗号拆分参数，忽略字符串和括号内部的逗号
+func splitStraceArgs(args string) []string {
+	var (
+		result  []string
+		depth   int
+		inQuote bool
+		start   int
+	)
+
+	for i := 0; i < len(args); i++ {
+		switch c := args[i]; {
+		case inQuote && c == '\\':
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			result = append(result, strings.TrimSpace(args[start:i]))
+			start = i + 1
+		}
+	}
+
+	if rest := strings.TrimSpace(args[start:]); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
+
+// straceReturnValue 解析系统调用的返回值，返回值为负数或无法解析时视为调用失败
+func straceReturnValue(result string) (int, bool) {
+	fields := strings.Fields(result)
+	if len(fields) == 0 {
+		return 0, false
+	}
+
+	// -y 参数会在返回的描述符后附加路径，如 3</etc/hosts>
+	ret, err := strconv.Atoi(straceFDNumber(fields[0]))
+	if err != nil || ret < 0 {
+		return 0, false
+	}
+	return ret, true
+}
+
+// straceResultPath 返回 -y 参数附加在返回值后的路径，如 3</etc/hosts>，没有时返回空字符串
+func straceResultPath(result string) string {
+	fields := strings.Fields(result)
+	if len(fields) == 0 {
+		return ""
+	}
+	return straceFDPath(fields[0])
+}
+
+// straceDuration 解析 -T 参数输出的调用耗时，如 = 3 <0.000012>
+func straceDuration(result string) time.Duration {
+	start := strings.LastIndex(result, "<")
+	if start < 0 || !strings.HasSuffix(result, ">") {
+		return 0
+	}
+
+	seconds, err := strconv.ParseFloat(result[start+1:len(result)-1], 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// straceErrno 解析调用失败时的错误码，如 = -1 ENOENT (No such file or directory)
+// strace只运行在Linux上，按错误名查找Linux的错误码，不依赖解析输出的系统
+func straceErrno(result string) int {
+	fields := strings.Fields(result)
+	if len(fields) < 2 || fields[0] != "-1" {
+		return 0
+	}
+	return straceErrnos[fields[1]]
+}
+
+// straceErrnos Linux错误名与错误码的对应关系
+var straceErrnos = map[string]int{
+	"EPERM":                 1,
+	"ENOENT":                2,
+	"ESRCH":                 3,
+	"EINTR":                 4,
+	"EIO":                   5,
+	"ENXIO":                 6,
+	"E2BIG":                 7,
+	"ENOEXEC":               8,
+	"EBADF":                 9,
+	"ECHILD":                10,
+	"EAGAIN":                11,
+	"EWOULDBLOCK":           11,
+	"ENOMEM":                12,
+	"EACCES":                13,
+	"EFAULT":                14,
+	"ENOTBLK":               15,
+	"EBUSY":                 16,
+	"EEXIST":                17,
+	"EXDEV":                 18,
+	"ENODEV":                19,
+	"ENOTDIR":               20,
+	"EISDIR":                21,
+	"EINVAL":                22,
+	"ENFILE":                23,
+	"EMFILE":                24,
+	"ENOTTY":                25,
+	"ETXTBSY":               26,
+	"EFBIG":                 27,
+	"ENOSPC":                28,
+	"ESPIPE":                29,
+	"EROFS":                 30,
+	"EMLINK":                31,
+	"EPIPE":                 32,
+	"EDOM":                  33,
+	"ERANGE":                34,
+	"EDEADLK":               35,
+	"ENAMETOOLONG":          36,
+	"ENOLCK":                37,
+	"ENOSYS":                38,
+	"ENOTEMPTY":             39,
+	"ELOOP":                 40,
+	"ENOMSG":                42,
+	"ENODATA":               61,
+	"ETIME":                 62,
+	"EOVERFLOW":             75,
+	"EBADFD":                77,
+	"EILSEQ":                84,
+	"EUSERS":                87,
+	"ENOTSOCK":              88,
+	"EOPNOTSUPP":            95,
+	"ENOTSUP":               95,
+	"EADDRINUSE":            98,
+	"ENETUNREACH":           101,
+	"ECONNRESET":            104,
+	"ENOBUFS":               105,
+	"ENOTCONN":              107,
+	"ETIMEDOUT":             110,
+	"ECONNREFUSED":          111,
+	"EHOSTUNREACH":          113,
+	"EALREADY":              114,
+	"EINPROGRESS":           115,
+	"ESTALE":                116,
+	"EDQUOT":                122,
+	"ENOMEDIUM":             123,
+	"ECANCELED":             125,
+	"ENOKEY":                126,
+	"EOWNERDEAD":            130,
+	"ERESTARTSYS":           512,
+	"ERESTARTNOINTR":        513,
+	"ERESTARTNOHAND":        514,
+	"ERESTART_RESTARTBLOCK": 516,
+}
+
+// straceArg 返回第index个参数的原始文本，参数不存在时返回空字符串
+func straceArg(args []string, index int) string {
+	if index >= len(args) {
+		return ""
+	}
+	return args[index]
+}
+
+// straceStringArg 返回第index个参数的字符串值，参数不是字符串时返回空字符串
+func straceStringArg(args []string, index int) string {
+	if index >= len(args) {
+		return ""
+	}
+
+	arg := args[index]
+	if !strings.HasPrefix(arg, "\"") {
+		return ""
+	}
+
+	// 去掉截断标记，如 "abc"...
+	arg = strings.TrimSuffix(arg, "...")
+	value, err := strconv.Unquote(arg)
+	if err != nil {
+		return strings.Trim(arg, "\"")
+	}
+	return value
+}
+
+// straceFDPath 返回 -y 参数输出的描述符路径，如 3</path/to/file>，没有路径时返回空字符串
+func straceFDPath(arg string) string {
+	if start := strings.Index(arg, "<"); start >= 0 && strings.HasSuffix(arg, ">") {
+		return arg[start+1 : len(arg)-1]
+	}
+	return ""
+}
+
+// straceFDNumber 返回文件描述符参数中的数字部分
+func straceFDNumber(arg string) string {
+	if idx := strings.Index(arg, "<"); idx >= 0 {
+		return arg[:idx]
+	}
+	return arg
+}
+
+// SetStraceOptions 设置strace事件源的配置
+func SetStraceOptions(options StraceOptions) {
+	straceOptions = options
+	if options.File != "" || options.PID > 0 || len(options.Command) > 0 {
+		log.Printf("已设置strace事件源参数: %s", strings.Join(GetStraceArgs(), " "))
+	}
+}
+
+// GetStraceOptions 获取strace事件源的配置
+func GetStraceOptions() StraceOptions {
+	return straceOptions
+}
+
+// ResetStraceOptions 重置strace事件源的配置
+func ResetStraceOptions() {
+	straceOptions = StraceOptions{}
+}
+
+// GetStraceArgs 返回实时运行strace时使用的参数，读取文件时只返回文件路径
+func GetStraceArgs() []string {
+	return straceArgs(straceOptions)
+}
+
+// straceArgs 返回按指定配置运行strace时使用的参数
+func straceArgs(options StraceOptions) []string {
+	if options.File != "" {
+		return []string{options.File}
+	}
+
+	args := []string{"-f", "-tt", "-T", "-y", "-o", "/dev/fd/3", "-e", "trace=file,read,write,close,pread64,pwrite64,readv,writev,clone,clone3,fork,vfork"}
+	if options.PID > 0 {
+		return append(args, "-p", strconv.Itoa(options.PID))
+	}
+	return append(append(args, "--"), options.Command...)
+}
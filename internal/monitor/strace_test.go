@@ -0,0 +1,170 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mine/fileWatch/internal/database"
+)
+
+func TestStraceParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []database.FileAccess
+	}{
+		{
+			name: "unfinished and resumed calls",
+			lines: []string{
+				`1234 openat(AT_FDCWD, "/data/a.txt", O_RDONLY <unfinished ...>`,
+				`1235 read(4, "x", 1) = 1`,
+				`1234 <... openat resumed>) = 3`,
+				`1234 read(3, "abc", 3) = 3`,
+				`1234 close(3) = 0`,
+				`1234 read(3, "abc", 3) = 3`,
+			},
+			want: []database.FileAccess{
+				{ProcessName: "1234", PID: 1234, TID: 1234, FilePath: "/data/a.txt", Operation: "open", FD: 3},
+				{ProcessName: "1234", PID: 1234, TID: 1234, FilePath: "/data/a.txt", Operation: "read", FD: 3, Bytes: 3},
+				{ProcessName: "1234", PID: 1234, TID: 1234, FilePath: "/data/a.txt", Operation: "close", FD: 3},
+			},
+		},
+		{
+			name: "clone, exec and exit",
+			lines: []string{
+				`100 clone(child_stack=NULL, flags=CLONE_CHILD_CLEARTID|SIGCHLD, child_tidptr=0x7f) = 101`,
+				`[pid   101] execve("/usr/bin/cat", ["cat", "/data/b"], 0x7ffd /* 10 vars */) = 0`,
+				`[pid   101] openat(AT_FDCWD, "/data/b", O_RDONLY) = 3`,
+				`100 clone(child_stack=0x7f, flags=CLONE_VM|CLONE_THREAD|CLONE_SIGHAND) = 102`,
+				`[pid   102] openat(AT_FDCWD, "/data/c", O_RDONLY) = 4`,
+				`100 read(4, "c", 1) = 1`,
+				`[pid   101] +++ exited with 0 +++`,
+				`[pid   102] +++ exited with 0 +++`,
+			},
+			want: []database.FileAccess{
+				{ProcessName: "100", PID: 101, PPID: 100, Operation: OperationFork},
+				{ProcessName: "cat", PID: 101, TID: 101, FilePath: "/usr/bin/cat", Operation: "execve"},
+				{ProcessName: "cat", PID: 101, TID: 101, FilePath: "/data/b", Operation: "open", FD: 3},
+				{ProcessName: "100", PID: 100, TID: 102, FilePath: "/data/c", Operation: "open", FD: 4},
+				{ProcessName: "100", PID: 100, TID: 100, FilePath: "/data/c", Operation: "read", FD: 4, Bytes: 1},
+				{ProcessName: "cat", PID: 101, Operation: OperationExit},
+			},
+		},
+		{
+			name: "working directory and directory descriptors",
+			lines: []string{
+				`200 openat(AT_FDCWD, "conf/a.yml", O_RDONLY) = 3`,
+				`200 chdir("/srv/app") = 0`,
+				`200 openat(AT_FDCWD, "conf/a.yml", O_RDONLY) = 3`,
+				`200 openat(AT_FDCWD, "/srv", O_RDONLY|O_DIRECTORY) = 4`,
+				`200 openat(4, "app/b.yml", O_RDONLY) = 5`,
+				`200 renameat(4, "x.tmp", AT_FDCWD, "x") = 0`,
+			},
+			want: []database.FileAccess{
+				{ProcessName: "200", PID: 200, TID: 200, FilePath: "conf/a.yml", Operation: "open", FD: 3},
+				{ProcessName: "200", PID: 200, TID: 200, FilePath: "/srv/app/conf/a.yml", Operation: "open", FD: 3},
+				{ProcessName: "200", PID: 200, TID: 200, FilePath: "/srv", Operation: "open", FD: 4},
+				{ProcessName: "200", PID: 200, TID: 200, FilePath: "/srv/app/b.yml", Operation: "open", FD: 5},
+				{ProcessName: "200", PID: 200, TID: 200, FilePath: "/srv/x.tmp", TargetPath: "/srv/app/x", Operation: "rename", FD: 4},
+			},
+		},
+		{
+			name: "paths annotated by -y",
+			lines: []string{
+				`300 openat(AT_FDCWD, "rel.txt", O_RDONLY) = 3</home/u/rel.txt>`,
+				`300 read(3</home/u/rel.txt>, "hi", 2) = 2`,
+				`300 write(4</home/u/out.log>, "hi", 2) = 2`,
+			},
+			want: []database.FileAccess{
+				{ProcessName: "300", PID: 300, TID: 300, FilePath: "/home/u/rel.txt", Operation: "open", FD: 3},
+				{ProcessName: "300", PID: 300, TID: 300, FilePath: "/home/u/rel.txt", Operation: "read", FD: 3, Bytes: 2},
+				{ProcessName: "300", PID: 300, TID: 300, FilePath: "/home/u/out.log", Operation: "write", FD: 4, Bytes: 2},
+			},
+		},
+		{
+			name: "errno and duration",
+			lines: []string{
+				`400 openat(AT_FDCWD, "/missing", O_RDONLY) = -1 ENOENT (No such file or directory) <0.000010>`,
+				`400 openat(AT_FDCWD, "/root/x", O_RDONLY) = -1 EACCES (Permission denied)`,
+				`400 write(5, "x", 1) = 1 <0.000250>`,
+			},
+			want: []database.FileAccess{
+				{ProcessName: "400", PID: 400, TID: 400, FilePath: "/missing", Operation: "open", Errno: 2, Duration: 10 * time.Microsecond},
+				{ProcessName: "400", PID: 400, TID: 400, FilePath: "/root/x", Operation: "open", Errno: 13},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := newStraceParser(time.Now(), false)
+			var got []database.FileAccess
+			for _, line := range tc.lines {
+				if access := parser.parseLine(line); access != nil {
+					access.Timestamp = time.Time{}
+					got = append(got, *access)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got:\n%+v\nwant:\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStraceTimestamps(t *testing.T) {
+	base := time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		// -tt 只有时刻，跨过午夜时切换到下一天
+		{`500 23:59:59.900000 openat(AT_FDCWD, "/t/a", O_RDONLY) = 3`, time.Date(2024, 1, 31, 23, 59, 59, 9e8, time.Local)},
+		{`500 00:00:00.100000 openat(AT_FDCWD, "/t/b", O_RDONLY) = 4`, time.Date(2024, 2, 1, 0, 0, 0, 1e8, time.Local)},
+		// -ttt 为Unix时间，不依赖基准日期
+		{`500 1706745600.250000 openat(AT_FDCWD, "/t/c", O_RDONLY) = 5`, time.Unix(1706745600, 25e7)},
+	}
+
+	parser := newStraceParser(base, false)
+	for _, tc := range tests {
+		access := parser.parseLine(tc.line)
+		if access == nil {
+			t.Fatalf("%q: no access", tc.line)
+		}
+		if !access.Timestamp.Equal(tc.want) {
+			t.Errorf("%q: timestamp = %v, want %v", tc.line, access.Timestamp, tc.want)
+		}
+	}
+}
+
+func TestStraceFileBase(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) *os.File {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { file.Close() })
+		return file
+	}
+
+	tt := write("tt.txt", `100 12:00:00.000100 openat(AT_FDCWD, "/a", O_RDONLY) = 3`+"\n")
+	if _, err := straceFileBase(tt, ""); err == nil {
+		t.Error("-tt file without date: expected error")
+	}
+	if base, err := straceFileBase(tt, "2024-01-31"); err != nil || !base.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("-tt file with date: %v, %v", base, err)
+	}
+
+	ttt := write("ttt.txt", `100 1706745600.250000 openat(AT_FDCWD, "/a", O_RDONLY) = 3`+"\n")
+	if _, err := straceFileBase(ttt, ""); err != nil {
+		t.Errorf("-ttt file without date: %v", err)
+	}
+}